@@ -0,0 +1,83 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sendgrid/rest"
+	"github.com/sendgrid/sendgrid-go"
+)
+
+// Page size of the paginated API requests
+var pageSize = 50
+
+// APIError is returned when SendGrid API responds with an unsuccessful status code.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("SendGrid API error (%d): %s", e.StatusCode, e.Body)
+}
+
+// Called with every API response (set by the rate limit aware sends and
+// the run report)
+var observeResponse = func(response *rest.Response) {}
+
+// Creates an authenticated request to the SendGrid v3 API.
+func newAPIRequest(apiKey string, method rest.Method, endpoint string) rest.Request {
+	request := sendgrid.GetRequest(apiKey, endpoint, apiHost)
+	request.Method = method
+	request.Headers["User-Agent"] = userAgent
+	if onBehalfOf != "" {
+		request.Headers["on-behalf-of"] = onBehalfOf
+	}
+	return request
+}
+
+// Makes the request to the SendGrid v3 API.
+func doAPIRequest(request rest.Request) (*rest.Response, error) {
+	response, err := sendgrid.API(request)
+	if err != nil {
+		return nil, err
+	}
+	logResponse(response)
+	observeResponse(response)
+	if response.StatusCode >= 300 {
+		return response, &APIError{StatusCode: response.StatusCode, Body: response.Body}
+	}
+	return response, nil
+}
+
+// Makes an authenticated request to the SendGrid v3 API.
+func apiRequest(apiKey string, method rest.Method, endpoint string,
+	queryParams map[string]string, body []byte) (*rest.Response, error) {
+	request := newAPIRequest(apiKey, method, endpoint)
+	request.QueryParams = queryParams
+	request.Body = body
+	return doAPIRequest(request)
+}
+
+// Makes an authenticated GET request and decodes the JSON response into v.
+func apiGet(apiKey, endpoint string, queryParams map[string]string, v interface{}) error {
+	response, err := apiRequest(apiKey, rest.Get, endpoint, queryParams, nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(response.Body), v)
+}
@@ -0,0 +1,62 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"github.com/spf13/pflag"
+)
+
+// Names of the attachment flags in the order they are given on the command line
+var attachmentFlagOrder []string
+
+// Flag value recording the order of the values given with the attachment flags
+type orderedValue struct {
+	pflag.Value
+	name string
+}
+
+func (v *orderedValue) Set(s string) error {
+	if err := v.Value.Set(s); err != nil {
+		return err
+	}
+	attachmentFlagOrder = append(attachmentFlagOrder, v.name)
+	return nil
+}
+
+// Makes the flags record the order of their values in attachmentFlagOrder.
+func recordAttachmentOrder(flags *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		f := flags.Lookup(name)
+		f.Value = &orderedValue{Value: f.Value, name: name}
+	}
+}
+
+// Interleaves the file (--att) and the inline (--att-inline) attachments in
+// the order of the flags. The attachments not covered by the order, eg, the
+// embedded images, follow the file and the inline ones.
+func orderAttachments(order []string, files, inline []*mail.Attachment) []*mail.Attachment {
+	attachments := make([]*mail.Attachment, 0, len(files)+len(inline))
+	for _, name := range order {
+		switch {
+		case name == "att" && len(files) > 0:
+			attachments, files = append(attachments, files[0]), files[1:]
+		case name == "att-inline" && len(inline) > 0:
+			attachments, inline = append(attachments, inline[0]), inline[1:]
+		}
+	}
+	attachments = append(attachments, files...)
+	return append(attachments, inline...)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestAttachmentOrder(t *testing.T) {
+	defer func(o []string) { attachmentFlagOrder = o }(attachmentFlagOrder)
+	attachmentFlagOrder = nil
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringArray("att", []string{}, "")
+	flags.StringArray("att-inline", []string{}, "")
+	recordAttachmentOrder(flags, "att", "att-inline")
+	err := flags.Parse([]string{"--att-inline", "a.txt:text/plain:A", "--att", "b.pdf",
+		"--att", "c.pdf", "--att-inline", "d.txt:text/plain:D"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"att-inline", "att", "att", "att-inline"}; !reflect.DeepEqual(attachmentFlagOrder, expected) {
+		t.Errorf("Expected the flag order %v, got %v", expected, attachmentFlagOrder)
+	}
+	if atts, _ := flags.GetStringArray("att"); !reflect.DeepEqual(atts, []string{"b.pdf", "c.pdf"}) {
+		t.Errorf("Expected the attachment values intact, got %v", atts)
+	}
+
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var filenames []string
+	for _, name := range []string{"b.pdf", "c.pdf"} {
+		filename := filepath.Join(dir, name)
+		ioutil.WriteFile(filename, []byte("PDF"), 0644)
+		filenames = append(filenames, filename)
+	}
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		attFilenames:     filenames,
+		inlineAtts: []*inlineAttachment{
+			{name: "a.txt", contentType: "text/plain", content: []byte("A")},
+			{name: "d.txt", contentType: "text/plain", content: []byte("D")},
+			{name: "logo.png", contentType: "image/png", content: []byte("PNG"), contentID: "logo"},
+		},
+		attOrder: attachmentFlagOrder,
+	}
+	var names []string
+	for _, a := range newV3Message(p).Attachments {
+		names = append(names, filepath.Base(a.Filename))
+	}
+	if expected := []string{"a.txt", "b.pdf", "c.pdf", "d.txt", "logo.png"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the attachments in the command-line order %v, got %v", expected, names)
+	}
+}
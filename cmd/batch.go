@@ -0,0 +1,207 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Pauses between the API calls unless the context gets cancelled
+// (can be replaced for testing)
+var sleep = func(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// Returned if the run gets interrupted
+var errInterrupted = errors.New("interrupted")
+
+// Returns the context cancelled on SIGINT and the function releasing it.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			log.Warn("Interrupted, finishing the in-flight send...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// Computes the interval between the API calls from the delay and the rate
+// (messages per minute). The longer one wins if both are given.
+func sendInterval(delay time.Duration, rate int) (time.Duration, error) {
+	if delay < 0 || rate < 0 {
+		return 0, errors.New("--delay and --rate cannot be negative")
+	}
+	if rate > 0 && time.Minute/time.Duration(rate) > delay {
+		delay = time.Minute / time.Duration(rate)
+	}
+	return delay, nil
+}
+
+// Splits the recipients into the batches of at most size recipients
+// (0 or less - a single batch).
+func splitRecipients(tos []string, size int) [][]string {
+	if size <= 0 || len(tos) <= size {
+		return [][]string{tos}
+	}
+	var batches [][]string
+	for len(tos) > size {
+		batches = append(batches, tos[:size])
+		tos = tos[size:]
+	}
+	return append(batches, tos)
+}
+
+// Maximum number of the personalizations of a SendGrid v3 API call
+const maxPersonalizations = 1000
+
+// Returns the batch size keeping the personalizations of the separate
+// messages within the API limit. The smaller batch size is kept as is.
+func personalizationBatchSize(p *sendParams, batchSize int) int {
+	if !p.separate || len(p.tos) <= maxPersonalizations ||
+		(batchSize > 0 && batchSize <= maxPersonalizations) {
+		return batchSize
+	}
+	log.Infof("Splitting %d personalizations into the API calls of at most %d.", len(p.tos), maxPersonalizations)
+	return maxPersonalizations
+}
+
+// Options of the sends made with multiple API calls
+type bulkOptions struct {
+	ctx       context.Context // stops scheduling the new API calls when cancelled
+	batchSize int             // maximum number of TO recipients per API call
+	interval  time.Duration   // pause between the API calls
+	failFast  bool            // abort on the first failure
+}
+
+// Returns the context of the run.
+func (o *bulkOptions) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// Sends the message in the batches of TO recipients (CC and BCC only with the
+// first one) and returns the first error.
+func sendInBatches(p *sendParams, opts *bulkOptions, deliver func(*sendParams) error) error {
+	batches := splitRecipients(p.tos, opts.batchSize)
+	ctx := opts.context()
+	var firstErr error
+	sent, failed := 0, 0
+	for i, tos := range batches {
+		if i > 0 && opts.interval > 0 {
+			sleep(ctx, opts.interval)
+		}
+		if ctx.Err() != nil {
+			log.Warnf("Interrupted after %d of %d batches.", i, len(batches))
+			if firstErr == nil {
+				firstErr = errInterrupted
+			}
+			break
+		}
+		batch := *p
+		batch.tos = tos
+		if i > 0 {
+			batch.ccs, batch.bccs = nil, nil
+		}
+		if err := deliver(&batch); err != nil {
+			log.Errorf("Failed to send the batch %d of %d: %v", i+1, len(batches), err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			if opts.failFast {
+				break
+			}
+		} else {
+			sent++
+		}
+	}
+	if len(batches) > 1 {
+		log.Infof("Sent %d of %d batches, %d failed.", sent, len(batches), failed)
+	}
+	return firstErr
+}
+
+// Number of the repeated sends above which the confirmation is required
+const repeatConfirmThreshold = 100
+
+// Asks the user to confirm the action reading the answer from the standard input.
+func confirm(prompt string) bool {
+	fmt.Fprintf(stdout, "%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// Sends the message n times pausing for the interval between the sends and
+// logs the aggregate timing with the success and failure counts. Unless failing
+// fast, all the sends are attempted, and the first error is returned if any of
+// them fails.
+func repeatSend(n int, opts *bulkOptions, send func() error) error {
+	ctx := opts.context()
+	var firstErr error
+	sent, failed := 0, 0
+	started := time.Now()
+	for i := 0; i < n; i++ {
+		if i > 0 && opts.interval > 0 {
+			sleep(ctx, opts.interval)
+		}
+		if ctx.Err() != nil {
+			log.Warnf("Interrupted after %d of %d messages.", i, n)
+			if firstErr == nil {
+				firstErr = errInterrupted
+			}
+			break
+		}
+		if err := send(); err != nil {
+			log.Errorf("Failed to send the message %d of %d: %v", i+1, n, err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			if opts.failFast {
+				break
+			}
+		} else {
+			sent++
+		}
+	}
+	elapsed := time.Since(started)
+	log.Infof("Sent %d of %d messages in %v (%.2f messages/s), %d failed.",
+		sent, n, elapsed, float64(sent+failed)/elapsed.Seconds(), failed)
+	return firstErr
+}
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitRecipients(t *testing.T) {
+	tos := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	for _, c := range []struct {
+		size     int
+		expected []int
+	}{
+		{0, []int{5}},
+		{2, []int{2, 2, 1}},
+		{5, []int{5}},
+		{10, []int{5}},
+	} {
+		batches := splitRecipients(tos, c.size)
+		if len(batches) != len(c.expected) {
+			t.Errorf("Expected %d batches of size %d, got %d", len(c.expected), c.size, len(batches))
+			continue
+		}
+		for i, b := range batches {
+			if len(b) != c.expected[i] {
+				t.Errorf("Expected batch %d of size %d to have %d recipients, got %d",
+					i, c.size, c.expected[i], len(b))
+			}
+		}
+	}
+}
+
+func TestSendInBatches(t *testing.T) {
+	calls := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	err := sendInBatches(p, &bulkOptions{batchSize: 2}, func(b *sendParams) error { return deliverV3("API-KEY", b) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 API calls, got %d", calls)
+	}
+}
+
+func TestSendInterval(t *testing.T) {
+	for _, c := range []struct {
+		delay    time.Duration
+		rate     int
+		expected time.Duration
+	}{
+		{0, 0, 0},
+		{time.Second, 0, time.Second},
+		{0, 30, 2 * time.Second},
+		{5 * time.Second, 30, 5 * time.Second},
+	} {
+		if interval, err := sendInterval(c.delay, c.rate); err != nil || interval != c.expected {
+			t.Errorf("Expected interval %v for delay %v and rate %d, got %v (%v)",
+				c.expected, c.delay, c.rate, interval, err)
+		}
+	}
+	if _, err := sendInterval(-time.Second, 0); err == nil {
+		t.Error("Expected an error for the negative delay")
+	}
+}
+
+func TestSendSeparatelyWithDelay(t *testing.T) {
+	var sleeps []time.Duration
+	defer func(s func(context.Context, time.Duration)) { sleep = s }(sleep)
+	sleep = func(ctx context.Context, d time.Duration) { sleeps = append(sleeps, d) }
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com", "c@example.com"},
+		separate:         true,
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	var sent []string
+	err := sendInBatches(p, &bulkOptions{batchSize: 1, interval: 2 * time.Second}, func(b *sendParams) error {
+		sent = append(sent, b.tos...)
+		if len(b.tos) != 1 {
+			t.Errorf("Expected an individual message, got %v", b.tos)
+		}
+		if b.tos[0] == "b@example.com" {
+			return fmt.Errorf("rejected")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected the failed message error")
+	}
+	if len(sent) != 3 {
+		t.Errorf("Expected all the messages to be attempted, got %v", sent)
+	}
+	if len(sleeps) != 2 || sleeps[0] != 2*time.Second || sleeps[1] != 2*time.Second {
+		t.Errorf("Expected 2 pauses of 2s between the messages, got %v", sleeps)
+	}
+}
+
+func TestRepeatSend(t *testing.T) {
+	calls := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	output := captureLog(func() {
+		if err := repeatSend(3, &bulkOptions{}, func() error { return deliverV3("API-KEY", p) }); err != nil {
+			t.Error(err)
+		}
+	})
+	if calls != 3 {
+		t.Errorf("Expected 3 sends, got %d", calls)
+	}
+	if !strings.Contains(output, "Sent 3 of 3 messages") {
+		t.Errorf("Expected the summary, got %q", output)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	defer func(r io.Reader, w io.Writer) { stdin, stdout = r, w }(stdin, stdout)
+	stdout = &bytes.Buffer{}
+	for answer, expected := range map[string]bool{"y\n": true, "Yes\n": true, "\n": false, "no\n": false} {
+		stdin = strings.NewReader(answer)
+		if confirm("Continue?") != expected {
+			t.Errorf("Expected %v for the answer %q", expected, answer)
+		}
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com", "c@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	for _, c := range []struct {
+		failFast bool
+		expected int
+	}{
+		{false, 3},
+		{true, 2},
+	} {
+		var sent []string
+		err := sendInBatches(p, &bulkOptions{batchSize: 1, failFast: c.failFast}, func(b *sendParams) error {
+			sent = append(sent, b.tos...)
+			if b.tos[0] == "b@example.com" {
+				return fmt.Errorf("rejected")
+			}
+			return nil
+		})
+		if err == nil {
+			t.Error("Expected the failed message error")
+		}
+		if len(sent) != c.expected {
+			t.Errorf("Expected %d messages attempted with fail-fast %v, got %v", c.expected, c.failFast, sent)
+		}
+
+		attempts := 0
+		repeatSend(3, &bulkOptions{failFast: c.failFast}, func() error {
+			attempts++
+			if attempts == 2 {
+				return fmt.Errorf("rejected")
+			}
+			return nil
+		})
+		if attempts != c.expected {
+			t.Errorf("Expected %d repeated sends with fail-fast %v, got %d", c.expected, c.failFast, attempts)
+		}
+	}
+}
+
+func TestSendInterrupted(t *testing.T) {
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var sent []string
+	var err error
+	output := captureLog(func() {
+		err = sendInBatches(p, &bulkOptions{ctx: ctx, batchSize: 1}, func(b *sendParams) error {
+			sent = append(sent, b.tos...)
+			if len(sent) == 2 {
+				cancel() // SIGINT during the second send
+			}
+			return nil
+		})
+	})
+	if err != errInterrupted || errorExitCode(err) != exitInterrupted {
+		t.Errorf("Expected the interruption error, got %v", err)
+	}
+	if len(sent) != 2 {
+		t.Errorf("Expected the in-flight send to finish and no new ones scheduled, got %v", sent)
+	}
+	if !strings.Contains(output, "Interrupted after 2 of 4 batches") || !strings.Contains(output, "Sent 2 of 4 batches") {
+		t.Errorf("Expected the summary of the partial completion, got %q", output)
+	}
+}
+
+func TestSplitPersonalizations(t *testing.T) {
+	var requests [][]map[string]interface{}
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Personalizations []map[string]interface{} `json:"personalizations"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, body.Personalizations)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+	defer func(q bool) { quiet = q }(quiet)
+	quiet = true
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		separate:         true,
+		subjects:         make(map[string]string),
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	for i := 0; i < 1500; i++ {
+		to := fmt.Sprintf("to%d@example.com", i)
+		p.tos = append(p.tos, to)
+		p.subjects[to] = fmt.Sprintf("Subject %d", i)
+	}
+	if size := personalizationBatchSize(p, 100); size != 100 {
+		t.Errorf("The smaller batch size should be kept, got %d", size)
+	}
+	opts := &bulkOptions{batchSize: personalizationBatchSize(p, 0)}
+	if err := sendInBatches(p, opts, func(b *sendParams) error { return deliverV3("API-KEY", b) }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 || len(requests[0]) != 1000 || len(requests[1]) != 500 {
+		t.Fatalf("Expected 1000 and 500 personalizations, got %d requests", len(requests))
+	}
+	last := requests[1][499]
+	if to := last["to"].([]interface{})[0].(map[string]interface{})["email"]; to != "to1499@example.com" ||
+		last["subject"] != "Subject 1499" {
+		t.Errorf("Expected the per-recipient subject to be preserved, got %v", last)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// blacklistCheckCmd represents the blacklist-check command
+var blacklistCheckCmd = &cobra.Command{
+	Use:   "blacklist-check",
+	Short: "Check the sending domain and IP address against the blocklists",
+	Long: `Looks up the sending domain and IP address in the common DNS blocklists
+and reports the listings, eg,
+
+sendgrid-cli blacklist-check --domain example.com --ip 192.0.2.1
+
+Exits with the code 5 if any of them is listed. Some blocklists refuse
+the queries made through the public DNS resolvers, those are reported as
+errors.
+`,
+	Run: blacklistCheck,
+}
+
+func init() {
+	RootCmd.AddCommand(blacklistCheckCmd)
+	blacklistCheckCmd.Flags().String("domain", "", "Sending domain.")
+	blacklistCheckCmd.Flags().String("ip", "", "Sending IPv4 address.")
+}
+
+// Blocklists of the domains
+var domainBlocklists = []string{"dbl.spamhaus.org", "multi.surbl.org", "multi.uribl.com"}
+
+// Blocklists of the IP addresses
+var ipBlocklists = []string{"zen.spamhaus.org", "bl.spamcop.net", "b.barracudacentral.org"}
+
+// Blocklist lookup statuses
+const (
+	blocklistListed    = "listed"
+	blocklistNotListed = "not listed"
+	blocklistError     = "error"
+)
+
+// Result of the blocklist lookup
+type blocklistCheck struct {
+	Blocklist string   `json:"blocklist"`
+	Query     string   `json:"query"`
+	Status    string   `json:"status"`
+	Codes     []string `json:"codes,omitempty"` // the addresses returned for the listing
+	Error     string   `json:"error,omitempty"`
+}
+
+// Looks up the name in the blocklist. The listed names resolve to 127.0.0.0/8
+// addresses, the blocklists respond with 127.255.255.0/24 to the refused queries.
+func checkBlocklist(blocklist, name string) blocklistCheck {
+	check := blocklistCheck{Blocklist: blocklist, Query: name + "." + blocklist, Status: blocklistNotListed}
+	addresses, err := lookupHost(check.Query)
+	if err != nil {
+		if e, ok := err.(*net.DNSError); !ok || !e.IsNotFound {
+			check.Status, check.Error = blocklistError, err.Error()
+		}
+		return check
+	}
+	for _, a := range addresses {
+		switch {
+		case strings.HasPrefix(a, "127.255.255."):
+			check.Status, check.Error = blocklistError, "the query was refused with "+a
+			return check
+		case strings.HasPrefix(a, "127."):
+			check.Status = blocklistListed
+			check.Codes = append(check.Codes, a)
+		}
+	}
+	return check
+}
+
+// Checks the domain and the IPv4 address (if not empty) against the blocklists.
+func checkBlocklists(domain, ip string) ([]blocklistCheck, error) {
+	var checks []blocklistCheck
+	if domain != "" {
+		for _, blocklist := range domainBlocklists {
+			checks = append(checks, checkBlocklist(blocklist, strings.ToLower(strings.TrimSuffix(domain, "."))))
+		}
+	}
+	if ip != "" {
+		v4 := net.ParseIP(ip).To4()
+		if v4 == nil {
+			return nil, fmt.Errorf("incorrect IPv4 address %q", ip)
+		}
+		reversed := fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0])
+		for _, blocklist := range ipBlocklists {
+			checks = append(checks, checkBlocklist(blocklist, reversed))
+		}
+	}
+	return checks, nil
+}
+
+func blacklistCheck(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	domain, ip := flagString(cmd, "domain"), flagString(cmd, "ip")
+	if domain == "" && ip == "" {
+		fail(exitUsage, "Either --domain or --ip is required.")
+	}
+	checks, err := checkBlocklists(domain, ip)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+
+	listed := 0
+	rows := make([][]string, len(checks))
+	for i, c := range checks {
+		if c.Status == blocklistListed {
+			listed++
+		}
+		rows[i] = []string{c.Blocklist, c.Query, c.Status, strings.Join(c.Codes, ",") + c.Error}
+	}
+	if err := printOutput(cmd, checks, []string{"BLOCKLIST", "QUERY", "STATUS", "DETAILS"}, rows); err != nil {
+		fail(exitUsage, err)
+	}
+	if listed > 0 {
+		failf(exitListed, "Listed on %d of %d blocklists.", listed, len(checks))
+	}
+}
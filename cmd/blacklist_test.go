@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestCheckBlocklists(t *testing.T) {
+	defer func(l func(string) ([]string, error)) { lookupHost = l }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		switch host {
+		case "example.com.dbl.spamhaus.org", "1.2.0.192.bl.spamcop.net":
+			return []string{"127.0.1.2"}, nil
+		case "example.com.multi.uribl.com":
+			return []string{"127.255.255.254"}, nil
+		case "1.2.0.192.zen.spamhaus.org":
+			return nil, errors.New("timeout")
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	checks, err := checkBlocklists("Example.com", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[string]string)
+	for _, c := range checks {
+		statuses[c.Query] = c.Status
+	}
+	expected := map[string]string{
+		"example.com.dbl.spamhaus.org":     blocklistListed,
+		"example.com.multi.surbl.org":      blocklistNotListed,
+		"example.com.multi.uribl.com":      blocklistError,
+		"1.2.0.192.zen.spamhaus.org":       blocklistError,
+		"1.2.0.192.bl.spamcop.net":         blocklistListed,
+		"1.2.0.192.b.barracudacentral.org": blocklistNotListed,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected the statuses %v, got %v", expected, statuses)
+	}
+	if checks[0].Codes[0] != "127.0.1.2" {
+		t.Errorf("Expected the listing code, got %+v", checks[0])
+	}
+
+	if _, err := checkBlocklists("", "2001:db8::1"); err == nil {
+		t.Error("Expected an error for the IPv6 address")
+	}
+}
+
+func TestBlacklistCheckListed(t *testing.T) {
+	defer func(l func(string) ([]string, error)) { lookupHost = l }(lookupHost)
+	lookupHost = func(host string) ([]string, error) { return []string{"127.0.1.2"}, nil }
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = ioutil.Discard
+	defer blacklistCheckCmd.Flags().Set("domain", "")
+
+	RootCmd.SetArgs([]string{"blacklist-check", "--domain", "example.com"})
+	captureLog(func() {
+		expectExit(t, 5, func() { RootCmd.Execute() })
+	})
+}
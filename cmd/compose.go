@@ -0,0 +1,86 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Checks if the standard input is a terminal (can be replaced for testing).
+var isTerminal = func() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Message composed interactively
+type draft struct {
+	tos     []string
+	subject string
+	body    string
+}
+
+// Prompts for the fields of the draft that are not given yet (the body is
+// ended with a line containing a single "." or EOF), previews the message and
+// asks for the confirmation.
+func compose(r io.Reader, w io.Writer, d *draft) error {
+	in := bufio.NewReader(r)
+	readLine := func(prompt string) string {
+		fmt.Fprint(w, prompt)
+		line, _ := in.ReadString('\n')
+		return strings.TrimSpace(line)
+	}
+
+	for len(d.tos) == 0 {
+		for _, to := range strings.Split(readLine("To (comma separated): "), ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				d.tos = append(d.tos, to)
+			}
+		}
+		if _, err := in.Peek(1); err == io.EOF && len(d.tos) == 0 {
+			return errors.New("no recipients given")
+		}
+	}
+	if d.subject == "" {
+		d.subject = readLine("Subject: ")
+	}
+	if d.body == "" {
+		fmt.Fprintln(w, `Body (end with a line containing a single "."):`)
+		var lines []string
+		for {
+			line, err := in.ReadString('\n')
+			if strings.TrimRight(line, "\r\n") == "." {
+				break
+			}
+			if line != "" {
+				lines = append(lines, strings.TrimRight(line, "\r\n"))
+			}
+			if err != nil {
+				break
+			}
+		}
+		d.body = strings.Join(lines, "\n")
+	}
+
+	fmt.Fprintf(w, "\nTo: %s\nSubject: %s\n\n%s\n\n", strings.Join(d.tos, ", "), d.subject, d.body)
+	if answer := strings.ToLower(readLine("Send the message? [y/N] ")); answer != "y" && answer != "yes" {
+		return errors.New("the message was not sent")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompose(t *testing.T) {
+	input := "a@example.com, b@example.com\nHello\nFirst line\nSecond line\n.\ny\n"
+	var out bytes.Buffer
+	d := &draft{}
+	if err := compose(strings.NewReader(input), &out, d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.tos) != 2 || d.tos[1] != "b@example.com" || d.subject != "Hello" || d.body != "First line\nSecond line" {
+		t.Errorf("Unexpected draft: %+v", d)
+	}
+	if !strings.Contains(out.String(), "To: a@example.com, b@example.com\nSubject: Hello") {
+		t.Errorf("Expected the preview, got:\n%s", out.String())
+	}
+
+	d = &draft{tos: []string{"a@example.com"}, subject: "Given"}
+	if err := compose(strings.NewReader("Body\n.\nn\n"), &out, d); err == nil {
+		t.Error("Expected an error if the sending is not confirmed")
+	}
+	if d.subject != "Given" || d.body != "Body" {
+		t.Errorf("Expected only the missing fields to be prompted, got %+v", d)
+	}
+
+	if err := compose(strings.NewReader(""), &out, &draft{}); err == nil {
+		t.Error("Expected an error without any recipients")
+	}
+}
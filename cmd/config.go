@@ -0,0 +1,174 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration file",
+}
+
+// configInitCmd represents the config init command
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write the given settings into the configuration file",
+	Long: `Writes the settings given on the command line together with FROM address and
+the tracking settings into the configuration file (default is $HOME/.sendgrid-cli.yaml), eg,
+
+sendgrid-cli config init -f sender@foo.bar --reply-to support@foo.bar --no-tracking
+
+The API key and the password are never written, use SENDGRID_API_KEY instead.
+`,
+	Run: configInit,
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd)
+}
+
+// Settings that can be stored in the configuration file
+var configSettings = []string{
+	"from", "from-name-fallback", "reply-to", "on-behalf-of", "subject-prefix", "no-tracking", "min-tls",
+	"timeout", "user-agent", "plain-pretty-tables", "output", "pretty", "max-recipients", "max-body-size",
+	"batch-size", "delay", "rate", "retries", "retry-backoff", "max-send-at-window",
+	"log-syslog", "syslog-address",
+}
+
+func configInit(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	filename := cfgFile
+	if filename == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			fail(exitUsage, err)
+		}
+		filename = filepath.Join(home, ".sendgrid-cli.yaml")
+	}
+	if _, err := os.Stat(filename); err == nil &&
+		!confirm(fmt.Sprintf("The configuration file %q exists, overwrite it?", filename)) {
+		fail(exitUsage, "Aborted.")
+	}
+	if cmd.Flags().Changed("key") || cmd.Flags().Changed("password") {
+		log.Warn("The API key and the password are not written into the configuration file.")
+	}
+	if err := writeConfig(filename, configValues(cmd)); err != nil {
+		log.Errorf("Failed to write the configuration file %q", filename)
+		fail(exitUsage, err)
+	}
+	log.Info("Configuration written to ", filename)
+}
+
+// Collects the settings given on the command line. FROM address and
+// the tracking settings are always included.
+func configValues(cmd *cobra.Command) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, name := range configSettings {
+		if cmd.Flags().Changed(name) {
+			values[name] = configValue(cmd, name)
+		}
+	}
+	if _, ok := values["from"]; !ok {
+		values["from"] = defaultFrom()
+	}
+	values["no-tracking"] = flagBool(cmd, "no-tracking")
+	return values
+}
+
+// Returns the flag value typed as it should appear in the configuration file.
+func configValue(cmd *cobra.Command, name string) interface{} {
+	switch cmd.Flag(name).Value.Type() {
+	case "bool":
+		return flagBool(cmd, name)
+	case "int":
+		return flagInt(cmd, name)
+	}
+	return flagString(cmd, name)
+}
+
+// Writes the settings into the YAML configuration file readable only by the user.
+func writeConfig(filename string, values map[string]interface{}) error {
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0600)
+}
+
+// Settings that can be given only in the environment sections of
+// the configuration file
+var environmentSettings = []string{"key", "host"}
+
+// Applies the settings from the configuration file to the flags not given
+// on the command line. The settings of the environment selected with --env
+// (the "environments.<name>" section) override the top-level ones.
+func applyConfig(cmd *cobra.Command) {
+	if noConfig {
+		if flagString(cmd, "env") != "" {
+			fail(exitUsage, "--env cannot be combined with --no-config.")
+		}
+		return
+	}
+	var envConfig *viper.Viper
+	if env := flagString(cmd, "env"); env != "" {
+		if envConfig = viper.Sub("environments." + env); envConfig == nil {
+			failf(exitUsage, "The environment %q is not defined in the configuration file.", env)
+		}
+	}
+	for _, name := range append(configSettings, environmentSettings...) {
+		value, ok := configSetting(envConfig, name)
+		if !ok {
+			continue
+		}
+		if name == "host" {
+			apiHost = value
+		} else if !cmd.Flags().Changed(name) {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				failf(exitUsage, "Incorrect %q in the configuration file: %v", name, err)
+			}
+		}
+	}
+}
+
+// Looks up the setting in the environment section first and then, unless it's
+// an environment-only setting, at the top level of the configuration file.
+func configSetting(envConfig *viper.Viper, name string) (string, bool) {
+	if envConfig != nil && envConfig.InConfig(name) {
+		return envConfig.GetString(name), true
+	}
+	for _, s := range environmentSettings {
+		if s == name {
+			return "", false
+		}
+	}
+	if viper.InConfig(name) {
+		return viper.GetString(name), true
+	}
+	return "", false
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfigInit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "config.yaml")
+
+	defer func(c string) { cfgFile = c }(cfgFile)
+	defer func() {
+		fromFlag.reset(placeholderFrom)
+		RootCmd.PersistentFlags().Lookup("from").Changed = false
+		RootCmd.PersistentFlags().Set("key", "")
+	}()
+	RootCmd.SetArgs([]string{"config", "init", "--config", filename,
+		"-f", "Sender <sender@example.com>", "-k", "SECRET-API-KEY"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := string(b)
+	if !strings.Contains(config, "from: Sender <sender@example.com>") || !strings.Contains(config, "no-tracking: false") {
+		t.Errorf("Expected the FROM address and the tracking settings, got:\n%s", config)
+	}
+	if strings.Contains(config, "SECRET-API-KEY") || strings.Contains(config, "key:") {
+		t.Errorf("The API key should not be written, got:\n%s", config)
+	}
+}
+
+func TestApplyConfigEnvironment(t *testing.T) {
+	filename := tempFile(t, `from: sender@example.com
+environments:
+  staging:
+    key: STAGING-KEY
+    host: https://api.staging.example.com
+    subject-prefix: "[STAGING] "
+`)
+	defer os.Remove(filename)
+	viper.SetConfigType("yaml")
+	viper.SetConfigFile(filename)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	defer viper.Reset()
+	defer func(h string) { apiHost = h }(apiHost)
+	resetFlags := func() {
+		for name, value := range map[string]string{
+			"env": "", "key": "", "subject-prefix": ""} {
+			RootCmd.PersistentFlags().Set(name, value)
+			RootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+		fromFlag.reset(placeholderFrom)
+		RootCmd.PersistentFlags().Lookup("from").Changed = false
+	}
+	resetFlags()
+	defer resetFlags()
+
+	applyConfig(RootCmd)
+	if flagString(RootCmd, "key") != "" || apiHost == "https://api.staging.example.com" {
+		t.Error("The environment settings should be applied only with --env")
+	}
+	RootCmd.PersistentFlags().Set("env", "staging")
+	applyConfig(RootCmd)
+	if key := flagString(RootCmd, "key"); key != "STAGING-KEY" {
+		t.Errorf("Expected the API key of the environment, got %q", key)
+	}
+	if apiHost != "https://api.staging.example.com" {
+		t.Errorf("Expected the API host of the environment, got %q", apiHost)
+	}
+	if prefix := flagString(RootCmd, "subject-prefix"); prefix != "[STAGING] " {
+		t.Errorf("Expected the subject prefix of the environment, got %q", prefix)
+	}
+	if from := flagString(RootCmd, "from"); from != "sender@example.com" {
+		t.Errorf("Expected the top-level FROM address, got %q", from)
+	}
+}
+
+func TestNoConfig(t *testing.T) {
+	filename := tempFile(t, "subject-prefix: \"[CONFIG] \"\n")
+	defer os.Remove(filename)
+	defer func(c string, n bool) { cfgFile, noConfig = c, n }(cfgFile, noConfig)
+	defer viper.Reset()
+	defer os.Setenv("SENDGRID_FROM", os.Getenv("SENDGRID_FROM"))
+	defer os.Setenv("SENDGRID_API_KEY", os.Getenv("SENDGRID_API_KEY"))
+	resetFlags := func() {
+		for name, value := range map[string]string{"subject-prefix": ""} {
+			RootCmd.PersistentFlags().Set(name, value)
+			RootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+	}
+	resetFlags()
+	defer resetFlags()
+	os.Setenv("SENDGRID_FROM", "env@example.com")
+	os.Setenv("SENDGRID_API_KEY", "ENV-API-KEY")
+
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	cfgFile, noConfig = filename, true
+	initConfig()
+	applyConfig(RootCmd)
+	if prefix := flagString(RootCmd, "subject-prefix"); prefix != "" {
+		t.Errorf("The config file should be ignored with --no-config, got the prefix %q", prefix)
+	}
+	if from := defaultFrom(); from != placeholderFrom {
+		t.Errorf("Expected the placeholder FROM address with --no-config, got %q", from)
+	}
+	if apiKey := getenv("SENDGRID_API_KEY"); apiKey != "" {
+		t.Errorf("Expected no API key from the environment with --no-config, got %q", apiKey)
+	}
+
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	noConfig = false
+	initConfig()
+	applyConfig(RootCmd)
+	if prefix := flagString(RootCmd, "subject-prefix"); prefix != "[CONFIG] " {
+		t.Errorf("Expected the prefix of the config file without --no-config, got %q", prefix)
+	}
+	if from := defaultFrom(); from != "env@example.com" {
+		t.Errorf("Expected the FROM address of the environment without --no-config, got %q", from)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Returns the shell command line running the command.
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// Runs the command with the shell and returns its standard output. The
+// command's standard error is reported if it exits with a non-zero code.
+func commandOutput(command string) (string, error) {
+	var stderr bytes.Buffer
+	c := shellCommand(command)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%q failed: %v: %s", command, err, message)
+		}
+		return "", fmt.Errorf("%q failed: %v", command, err)
+	}
+	return string(out), nil
+}
+
+// Runs the content command given with the option and returns its output.
+func commandContent(option, command string, trim bool) string {
+	out, err := commandOutput(command)
+	if err != nil {
+		failf(exitUsage, "Failed to generate the content with %s: %v", option, err)
+	}
+	if trim {
+		return strings.TrimSpace(out)
+	}
+	return out
+}
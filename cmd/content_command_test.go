@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestContentCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are run with sh")
+	}
+	htmlContent, plainTextContent := resolveContent(&contentSources{
+		htmlCommand:      "echo '<p>Generated</p>'",
+		plainTextCommand: "echo Generated",
+		trim:             true,
+	})
+	if htmlContent != "<p>Generated</p>" || plainTextContent != "Generated" {
+		t.Errorf("Expected the command output as the content, got %q and %q", htmlContent, plainTextContent)
+	}
+
+	htmlContent, plainTextContent = resolveContent(&contentSources{htmlCommand: "echo '<p>Generated</p>'"})
+	if htmlContent != "<p>Generated</p>\n" || plainTextContent != "Generated" {
+		t.Errorf("Expected the plain text converted from the command output, got %q and %q",
+			htmlContent, plainTextContent)
+	}
+
+	if _, err := commandOutput("echo 'no such report' >&2; exit 3"); err == nil ||
+		!strings.Contains(err.Error(), "no such report") {
+		t.Errorf("Expected the error with the command's stderr, got %v", err)
+	}
+	expectExit(t, exitUsage, func() { resolveContent(&contentSources{plainTextCommand: "exit 1"}) })
+	expectExit(t, exitUsage, func() {
+		resolveContent(&contentSources{plainTextCommand: "echo Generated", plainText: "Inline text"})
+	})
+}
@@ -0,0 +1,57 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sendgrid/rest"
+)
+
+// Quotes the argument for the POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Returns the curl command making the same API request. The API key is
+// replaced with $SENDGRID_API_KEY, so that the command can be shared.
+func curlCommand(request rest.Request) string {
+	url := request.BaseURL
+	if len(request.QueryParams) > 0 {
+		url = rest.AddQueryParameters(url, request.QueryParams)
+	}
+	parts := []string{"curl", "-X", string(request.Method), shellQuote(url)}
+	names := make([]string, 0, len(request.Headers))
+	for name := range request.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := request.Headers[name]
+		if strings.EqualFold(name, "Authorization") {
+			parts = append(parts, "-H", `"`+name+`: Bearer $SENDGRID_API_KEY"`)
+			continue
+		}
+		parts = append(parts, "-H", shellQuote(name+": "+value))
+	}
+	if _, ok := request.Headers["Content-Type"]; !ok && len(request.Body) > 0 {
+		parts = append(parts, "-H", shellQuote("Content-Type: application/json"))
+	}
+	if len(request.Body) > 0 {
+		parts = append(parts, "--data-binary", shellQuote(string(request.Body)))
+	}
+	return strings.Join(parts, " ")
+}
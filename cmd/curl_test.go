@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCurlCommand(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, w io.Writer, q bool) { apiHost, stdout, quiet = h, w, q }(apiHost, stdout, quiet)
+	var out bytes.Buffer
+	apiHost, stdout, quiet = fakeServer.URL, &out, true
+
+	err := deliverV3("SG.SECRET-KEY", &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "It's a test",
+		plainTextContent: "Test",
+		dumpCurl:         true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	curl := out.String()
+	for _, expected := range []string{
+		"curl -X POST '" + fakeServer.URL + "/v3/mail/send'",
+		`-H "Authorization: Bearer $SENDGRID_API_KEY"`,
+		"-H 'Content-Type: application/json'",
+		`"subject":"It'\''s a test"`,
+	} {
+		if !strings.Contains(curl, expected) {
+			t.Errorf("Expected %q in the curl command, got:\n%s", expected, curl)
+		}
+	}
+	if strings.Contains(curl, "SECRET-KEY") {
+		t.Errorf("Expected the API key to be masked, got:\n%s", curl)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Looks up the canonical name of the host (can be replaced for testing).
+var lookupCNAME = net.LookupCNAME
+
+// Looks up the addresses of the host (can be replaced for testing).
+var lookupHost = net.LookupHost
+
+// Looks up the MX records of the domain (can be replaced for testing).
+var lookupMX = net.LookupMX
+
+// DKIM selectors set up for the authenticated SendGrid domains
+var dkimSelectors = []string{"s1", "s2"}
+
+// Returns the warnings about the SendGrid DKIM records missing for the domain.
+// It's a best-effort check: any lookup failure is treated as a missing record.
+func dkimWarnings(domain string) []string {
+	var warnings []string
+	for _, selector := range dkimSelectors {
+		host := selector + "._domainkey." + domain
+		cname, err := lookupCNAME(host)
+		if err != nil || strings.TrimSuffix(cname, ".") == host {
+			warnings = append(warnings, fmt.Sprintf(
+				"The DKIM record %s is missing, the messages from %s may fail the authentication", host, domain))
+		}
+	}
+	return warnings
+}
+
+// Returns the recipient domains that have no MX records, looking up each
+// domain once. The "null MX" record (".") and any lookup failure are treated
+// as no MX records.
+func domainsWithoutMX(addresses []string) []string {
+	checked := make(map[string]bool)
+	var missing []string
+	for _, address := range addresses {
+		domain := addressDomain(address)
+		if checked[domain] {
+			continue
+		}
+		checked[domain] = true
+		records, err := lookupMX(domain)
+		hasMX := false
+		for _, r := range records {
+			if strings.TrimSuffix(r.Host, ".") != "" {
+				hasMX = true
+			}
+		}
+		if !hasMX {
+			if err != nil {
+				log.Debugf("MX lookup of %s failed: %v", domain, err)
+			}
+			missing = append(missing, domain)
+		}
+	}
+	return missing
+}
+
+// Warns about the recipient domains without MX records or rejects them if strict.
+func checkMX(addresses []string, strict bool) error {
+	missing := domainsWithoutMX(addresses)
+	if len(missing) == 0 {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("the recipient domains have no MX records: %s", strings.Join(missing, ", "))
+	}
+	for _, domain := range missing {
+		log.Warnf("The recipient domain %s has no MX records, the messages to it will bounce.", domain)
+	}
+	return nil
+}
+
+// Returns the domain part of the email address.
+func addressDomain(address string) string {
+	return strings.ToLower(address[strings.LastIndex(address, "@")+1:])
+}
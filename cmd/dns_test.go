@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestDKIMWarnings(t *testing.T) {
+	defer func(l func(string) (string, error)) { lookupCNAME = l }(lookupCNAME)
+	lookupCNAME = func(host string) (string, error) {
+		switch host {
+		case "s1._domainkey.example.com", "s2._domainkey.example.com":
+			return "s1.domainkey.u123.wl.sendgrid.net.", nil
+		case "s1._domainkey.partial.com":
+			return "s1.domainkey.u123.wl.sendgrid.net.", nil
+		}
+		return "", &net.DNSError{Err: "no such host", Name: host}
+	}
+
+	if warnings := dkimWarnings("example.com"); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for the domain with DKIM records, got %v", warnings)
+	}
+	if warnings := dkimWarnings("partial.com"); len(warnings) != 1 {
+		t.Errorf("Expected a warning for the missing s2 record, got %v", warnings)
+	}
+	if warnings := dkimWarnings("nodkim.com"); len(warnings) != 2 {
+		t.Errorf("Expected warnings for both missing records, got %v", warnings)
+	}
+
+	lookupCNAME = func(host string) (string, error) { return "", errors.New("timeout") }
+	if warnings := dkimWarnings("example.com"); len(warnings) != 2 {
+		t.Errorf("Expected the lookup failures to be reported as missing records, got %v", warnings)
+	}
+}
+
+func TestAddressDomain(t *testing.T) {
+	if d := addressDomain("John@Example.COM"); d != "example.com" {
+		t.Errorf("Unexpected domain %q", d)
+	}
+}
+
+func TestCheckMX(t *testing.T) {
+	defer func(l func(string) ([]*net.MX, error)) { lookupMX = l }(lookupMX)
+	lookups := make(map[string]int)
+	lookupMX = func(domain string) ([]*net.MX, error) {
+		lookups[domain]++
+		switch domain {
+		case "example.com":
+			return []*net.MX{{Host: "mx.example.com.", Pref: 10}}, nil
+		case "nullmx.com":
+			return []*net.MX{{Host: ".", Pref: 0}}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: domain}
+	}
+
+	addresses := []string{"a@example.com", "b@nomx.com", "c@Example.com", "d@nomx.com", "e@nullmx.com"}
+	missing := domainsWithoutMX(addresses)
+	if len(missing) != 2 || missing[0] != "nomx.com" || missing[1] != "nullmx.com" {
+		t.Errorf("Expected nomx.com and nullmx.com to be flagged, got %v", missing)
+	}
+	if lookups["example.com"] != 1 || lookups["nomx.com"] != 1 {
+		t.Errorf("Expected a single lookup per domain, got %v", lookups)
+	}
+
+	if err := checkMX(addresses, true); err == nil || !strings.Contains(err.Error(), "nomx.com") {
+		t.Errorf("Expected the strict check to fail for nomx.com, got %v", err)
+	}
+	output := captureLog(func() {
+		if err := checkMX(addresses, false); err != nil {
+			t.Error(err)
+		}
+	})
+	if !strings.Contains(output, "nomx.com has no MX records") {
+		t.Errorf("Expected the warning about nomx.com, got %q", output)
+	}
+	if err := checkMX([]string{"a@example.com"}, true); err != nil {
+		t.Errorf("Expected the domain with MX records to pass, got %v", err)
+	}
+}
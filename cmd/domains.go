@@ -0,0 +1,95 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// domainsCmd represents the domains command
+var domainsCmd = &cobra.Command{
+	Use:   "domains",
+	Short: "Manage authenticated sender domains",
+}
+
+// domainsListCmd represents the domains list command
+var domainsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List authenticated sender domains",
+	Long: `Lists the authenticated (whitelabeled) sender domains of the account with
+their subdomains and whether they are valid and default, eg,
+
+sendgrid-cli domains list -k API-KEY
+`,
+	Run: domainsList,
+}
+
+func init() {
+	RootCmd.AddCommand(domainsCmd)
+	domainsCmd.AddCommand(domainsListCmd)
+}
+
+// Authenticated sender domain
+type authenticatedDomain struct {
+	ID        int    `json:"id"`
+	Domain    string `json:"domain"`
+	Subdomain string `json:"subdomain"`
+	Username  string `json:"username"`
+	Valid     bool   `json:"valid"`
+	Default   bool   `json:"default"`
+}
+
+func domainsList(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	domains, err := fetchDomains(apiKeyFlag(cmd))
+	if err != nil {
+		log.Error("Failed to retrieve the authenticated domains.")
+		fail(errorExitCode(err), err)
+	}
+
+	rows := make([][]string, len(domains))
+	for i, d := range domains {
+		rows[i] = []string{d.Domain, d.Subdomain, strconv.FormatBool(d.Valid), strconv.FormatBool(d.Default)}
+	}
+	err = printOutput(cmd, domains, []string{"DOMAIN", "SUBDOMAIN", "VALID", "DEFAULT"}, rows)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+}
+
+// Retrieves all the authenticated domains page by page.
+func fetchDomains(apiKey string) ([]authenticatedDomain, error) {
+	var domains []authenticatedDomain
+	for offset := 0; ; offset += pageSize {
+		var page []authenticatedDomain
+		err := apiGet(apiKey, "/v3/whitelabel/domains", map[string]string{
+			"limit":  strconv.Itoa(pageSize),
+			"offset": strconv.Itoa(offset),
+		}, &page)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, page...)
+		if len(page) < pageSize {
+			return domains, nil
+		}
+	}
+}
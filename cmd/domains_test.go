@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestFetchDomains(t *testing.T) {
+	all := []authenticatedDomain{
+		{ID: 1, Domain: "example.com", Subdomain: "em1", Valid: true, Default: true},
+		{ID: 2, Domain: "example.net", Subdomain: "em2", Valid: false},
+		{ID: 3, Domain: "example.org", Subdomain: "em3", Valid: true},
+	}
+	requests := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		end := offset + limit
+		if end > len(all) {
+			end = len(all)
+		}
+		json.NewEncoder(w).Encode(all[offset:end])
+	}))
+	defer fakeServer.Close()
+	defer func(h string, s int) { apiHost, pageSize = h, s }(apiHost, pageSize)
+	apiHost, pageSize = fakeServer.URL, 2
+
+	domains, err := fetchDomains("API-KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 3 || domains[2].Domain != "example.org" {
+		t.Errorf("Expected all the domains, got: %+v", domains)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 page requests, got %d", requests)
+	}
+}
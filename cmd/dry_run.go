@@ -0,0 +1,77 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+)
+
+// Prints the message that would be sent without sending it. If the message uses
+// a template with the dynamic template data, the data gets linted against
+// the active version of the template. In the sandbox mode no API calls are made.
+// With --dump-curl the curl command making the v3 API request is printed instead.
+func dryRun(apiKey string, p *sendParams) {
+	w := stdout
+	if p.dryRunOut != nil {
+		w = p.dryRunOut
+	}
+	if p.dumpCurl && apiKey != "" {
+		request, err := mailSendRequest(apiKey, p)
+		if err != nil {
+			fail(exitUsage, err)
+		}
+		fmt.Fprintln(w, curlCommand(request))
+	} else {
+		var body []byte
+		var err error
+		if apiKey == "" {
+			body, err = json.Marshal(newV2Mail(p))
+		} else {
+			body, err = requestBody(p)
+		}
+		if err != nil {
+			fail(exitUsage, err)
+		}
+		var out bytes.Buffer
+		json.Indent(&out, body, "", "  ")
+		fmt.Fprintln(w, out.String())
+	}
+
+	if p.sandbox {
+		log.Info("Dry run: the sandbox validation was skipped, the message was neither validated by SendGrid nor sent.")
+		return
+	}
+	if p.templateID != "" && len(p.templateData) > 0 {
+		if apiKey == "" {
+			log.Warn("The template data validation requires SendGrid API key, skipping it.")
+			return
+		}
+		rest.DefaultClient.HTTPClient.Transport = newTransport()
+		warnings, err := templateDataWarnings(apiKey, p.templateID, p.templateData)
+		if err != nil {
+			log.Error("Failed to validate the template data.")
+			fail(errorExitCode(err), err)
+		}
+		for _, w := range warnings {
+			log.Warn(w)
+		}
+	}
+	log.Info("Dry run: the message was not sent.")
+}
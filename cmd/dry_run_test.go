@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDryRunTemplateData(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("Dry run should not send the message: %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprintln(w, `{"id": "TEMPLATE-ID", "versions": [{"active": 1, "html_content": "{{name}} {{code}}"}]}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, w io.Writer) { apiHost, stdout = h, w }(apiHost, stdout)
+	var out bytes.Buffer
+	apiHost, stdout = fakeServer.URL, &out
+
+	output := captureLog(func() {
+		dryRun("API-KEY", &sendParams{
+			from:         "sender@example.com",
+			tos:          []string{"to@example.com"},
+			subject:      "Test",
+			htmlContent:  dummyContent,
+			templateID:   "TEMPLATE-ID",
+			templateData: map[string]interface{}{"name": "John", "extra": true},
+		})
+	})
+	if !strings.Contains(output, `\"code\", but it is not supplied`) ||
+		!strings.Contains(output, `\"extra\" is supplied in --data, but not used`) {
+		t.Errorf("Expected warnings about the mismatched keys, got:\n%s", output)
+	}
+	if !strings.Contains(out.String(), `"dynamic_template_data"`) {
+		t.Errorf("Expected the message to be printed, got:\n%s", out.String())
+	}
+}
+
+func TestDryRunSandbox(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected API call in the dry run: %s %s", r.Method, r.URL.Path)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, w io.Writer) { apiHost, stdout = h, w }(apiHost, stdout)
+	var out bytes.Buffer
+	apiHost, stdout = fakeServer.URL, &out
+
+	output := captureLog(func() {
+		dryRun("API-KEY", &sendParams{
+			from:         "sender@example.com",
+			tos:          []string{"to@example.com"},
+			subject:      "Test",
+			htmlContent:  dummyContent,
+			templateID:   "TEMPLATE-ID",
+			templateData: map[string]interface{}{"name": "John"},
+			sandbox:      true,
+		})
+	})
+	if !strings.Contains(output, "sandbox validation was skipped") {
+		t.Errorf("Expected a note about the skipped sandbox validation, got:\n%s", output)
+	}
+	if !strings.Contains(out.String(), `"sandbox_mode"`) {
+		t.Errorf("Expected the message with the sandbox mode to be printed, got:\n%s", out.String())
+	}
+}
+
+func TestDryRunSandboxTemplateName(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected API call in the dry run: %s %s", r.Method, r.URL.Path)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, w io.Writer) { apiHost, stdout = h, w }(apiHost, stdout)
+	var out bytes.Buffer
+	apiHost, stdout = fakeServer.URL, &out
+
+	toFile := tempFile(t, "to@example.com\n")
+	defer os.Remove(toFile)
+	defer func() {
+		for name, value := range map[string]string{
+			"key": "", "to-file": "", "subject": "", "template-name": "", "sandbox": "false", "dry-run": "false"} {
+			RootCmd.PersistentFlags().Set(name, value)
+			RootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+	}()
+	RootCmd.SetArgs([]string{"-k", "API-KEY", "--to-file", toFile, "-s", "Test",
+		"--template-name", "Welcome", "--sandbox", "--dry-run"})
+	output := captureLog(func() {
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(output, `The template name \"Welcome\" is not looked up`) {
+		t.Errorf("Expected the warning about the unresolved template name, got:\n%s", output)
+	}
+	if !strings.Contains(out.String(), `"template_id": "Welcome"`) {
+		t.Errorf("Expected the unresolved template name in the message, got:\n%s", out.String())
+	}
+}
+
+func TestDryRunOut(t *testing.T) {
+	defer func(w io.Writer) { stdout = w }(stdout)
+	var out, dayOut bytes.Buffer
+	stdout = &out
+
+	dryRun("API-KEY", &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		dryRunOut:        &dayOut,
+	})
+	if out.Len() != 0 || !strings.Contains(dayOut.String(), `"subject": "Test"`) {
+		t.Errorf("Expected the message printed apart from the standard output, got %q and %q", out.String(), dayOut.String())
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show recent email activity",
+	Long: `Queries SendGrid Email Activity API for the recent messages and prints
+their IDs, recipients, subjects, statuses and the last event times, eg,
+
+sendgrid-cli events -k API-KEY -t recepient@domain.net --status delivered --limit 20
+
+The Email Activity API needs to be enabled for the account.
+`,
+	Run: events,
+}
+
+func init() {
+	RootCmd.AddCommand(eventsCmd)
+	eventsCmd.Flags().String("status", "", "Message status filter (processed, delivered, not_delivered).")
+	eventsCmd.Flags().Int("limit", 10, "Maximum number of the messages to show.")
+}
+
+// Email activity message entry
+type messageEvent struct {
+	MsgID         string `json:"msg_id"`
+	FromEmail     string `json:"from_email"`
+	ToEmail       string `json:"to_email"`
+	Subject       string `json:"subject"`
+	Status        string `json:"status"`
+	OpensCount    int    `json:"opens_count"`
+	ClicksCount   int    `json:"clicks_count"`
+	LastEventTime string `json:"last_event_time"`
+}
+
+func events(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	messages, err := fetchEvents(apiKeyFlag(cmd),
+		flagStringArray(cmd, "to"), flagString(cmd, "status"), flagInt(cmd, "limit"))
+	if err != nil {
+		log.Error("Failed to retrieve the email activity.")
+		fail(errorExitCode(err), err)
+	}
+
+	rows := make([][]string, len(messages))
+	for i, m := range messages {
+		rows[i] = []string{m.MsgID, m.ToEmail, m.Subject, m.Status, m.LastEventTime}
+	}
+	err = printOutput(cmd, messages, []string{"MESSAGE ID", "TO", "SUBJECT", "STATUS", "LAST EVENT"}, rows)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+}
+
+// Builds the Email Activity query from the filters.
+func eventsQuery(tos []string, status string) string {
+	var conditions []string
+	if len(tos) > 0 {
+		toConditions := make([]string, len(tos))
+		for i, to := range tos {
+			toConditions[i] = fmt.Sprintf("to_email=%q", createAddress(to).Address)
+		}
+		if len(toConditions) == 1 {
+			conditions = append(conditions, toConditions[0])
+		} else {
+			conditions = append(conditions, "("+strings.Join(toConditions, " OR ")+")")
+		}
+	}
+	if status != "" {
+		conditions = append(conditions, fmt.Sprintf("status=%q", status))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// Retrieves the recent messages from the Email Activity API.
+func fetchEvents(apiKey string, tos []string, status string, limit int) ([]messageEvent, error) {
+	queryParams := map[string]string{"limit": strconv.Itoa(limit)}
+	if query := eventsQuery(tos, status); query != "" {
+		queryParams["query"] = query
+	}
+	var result struct {
+		Messages []messageEvent `json:"messages"`
+	}
+	err := apiGet(apiKey, "/v3/messages", queryParams, &result)
+	if e, ok := err.(*APIError); ok && e.StatusCode == http.StatusForbidden {
+		e.Body = "the Email Activity API is not enabled for this account or the API key lacks the access: " + e.Body
+		return nil, e
+	}
+	return result.Messages, err
+}
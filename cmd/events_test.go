@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEventsQuery(t *testing.T) {
+	for _, c := range []struct {
+		tos      []string
+		status   string
+		expected string
+	}{
+		{nil, "", ""},
+		{[]string{"a@example.com"}, "", `to_email="a@example.com"`},
+		{[]string{"A <a@example.com>", "b@example.com"}, "delivered",
+			`(to_email="a@example.com" OR to_email="b@example.com") AND status="delivered"`},
+	} {
+		if q := eventsQuery(c.tos, c.status); q != c.expected {
+			t.Errorf("Expected query %q, got %q", c.expected, q)
+		}
+	}
+}
+
+func TestFetchEvents(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/messages" {
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+		if r.URL.Query().Get("limit") != "5" || r.URL.Query().Get("query") != `status="delivered"` {
+			t.Errorf("Unexpected query %q", r.URL.RawQuery)
+		}
+		fmt.Fprintln(w, `{"messages": [{"msg_id": "MSG-1", "to_email": "a@example.com",
+			"subject": "Hello", "status": "delivered", "last_event_time": "2017-10-01T10:00:00Z"}]}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	messages, err := fetchEvents("API-KEY", nil, "delivered", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].MsgID != "MSG-1" || messages[0].Status != "delivered" {
+		t.Errorf("Unexpected messages: %v", messages)
+	}
+}
+
+func TestFetchEventsNotEnabled(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintln(w, `{"errors": [{"message": "access forbidden"}]}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	_, err := fetchEvents("API-KEY", nil, "", 10)
+	if err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("Expected a clear error on 403, got: %v", err)
+	}
+}
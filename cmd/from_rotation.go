@@ -0,0 +1,88 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// FROM address rotation strategies
+const (
+	fromRoundRobin = "round-robin"
+	fromRandom     = "random"
+)
+
+// Value of --from: the first address is the sender, the repeated flags add
+// the addresses rotated between the messages with --from-rotation.
+type fromAddresses struct {
+	addresses []string
+	changed   bool
+}
+
+func (f *fromAddresses) Set(address string) error {
+	if !f.changed {
+		f.addresses, f.changed = nil, true // the first given address replaces the default
+	}
+	f.addresses = append(f.addresses, address)
+	return nil
+}
+
+func (f *fromAddresses) String() string {
+	if len(f.addresses) == 0 {
+		return ""
+	}
+	return f.addresses[0]
+}
+
+// The type of a single address flag, so that the first address is read with GetString.
+func (f *fromAddresses) Type() string {
+	return "string"
+}
+
+// Resets the addresses to the single one as if the flag was never given.
+func (f *fromAddresses) reset(address string) {
+	f.addresses, f.changed = []string{address}, false
+}
+
+// FROM addresses given with --from
+var fromFlag = &fromAddresses{addresses: []string{placeholderFrom}}
+
+// Picks a random index below n (can be replaced for testing).
+var randomIndex = rand.Intn
+
+// Wraps the delivery assigning the FROM addresses to the messages in turn
+// (round-robin) or at random.
+func withFromRotation(froms []string, strategy string, deliver func(*sendParams) error) (func(*sendParams) error, error) {
+	var next func() string
+	switch strategy {
+	case fromRoundRobin:
+		i := 0
+		next = func() string {
+			from := froms[i%len(froms)]
+			i++
+			return from
+		}
+	case fromRandom:
+		next = func() string { return froms[randomIndex(len(froms))] }
+	default:
+		return nil, fmt.Errorf("unsupported FROM rotation %q, use %s or %s", strategy, fromRoundRobin, fromRandom)
+	}
+	return func(p *sendParams) error {
+		message := *p
+		message.from = next()
+		return deliver(&message)
+	}, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFromAddressesFlag(t *testing.T) {
+	f := &fromAddresses{addresses: []string{placeholderFrom}}
+	if f.String() != placeholderFrom {
+		t.Errorf("Expected the default address, got %q", f.String())
+	}
+	f.Set("a@example.com")
+	f.Set("b@example.com")
+	if expected := []string{"a@example.com", "b@example.com"}; !reflect.DeepEqual(f.addresses, expected) {
+		t.Errorf("Expected %v, got %v", expected, f.addresses)
+	}
+	if f.String() != "a@example.com" {
+		t.Errorf("Expected the first address, got %q", f.String())
+	}
+	f.reset(placeholderFrom)
+	f.Set("c@example.com")
+	if expected := []string{"c@example.com"}; !reflect.DeepEqual(f.addresses, expected) {
+		t.Errorf("Expected %v after the reset, got %v", expected, f.addresses)
+	}
+}
+
+func TestFromRotation(t *testing.T) {
+	var froms []string
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		var message struct {
+			From struct {
+				Address string `json:"email"`
+			} `json:"from"`
+		}
+		if err := json.Unmarshal(b, &message); err != nil {
+			t.Error(err)
+		}
+		froms = append(froms, message.From.Address)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	p := &sendParams{
+		from:             "a@example.com",
+		froms:            []string{"a@example.com", "b@example.com"},
+		tos:              []string{"1@example.com", "2@example.com", "3@example.com", "4@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	deliver, err := withFromRotation(p.froms, fromRoundRobin,
+		func(b *sendParams) error { return deliverV3("API-KEY", b) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sendInBatches(p, &bulkOptions{batchSize: 1}, deliver); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a@example.com", "b@example.com", "a@example.com", "b@example.com"}
+	if !reflect.DeepEqual(froms, expected) {
+		t.Errorf("Expected the FROM addresses %v, got %v", expected, froms)
+	}
+
+	defer func(r func(int) int) { randomIndex = r }(randomIndex)
+	randomIndex = func(n int) int { return n - 1 }
+	froms = nil
+	if deliver, err = withFromRotation(p.froms, fromRandom,
+		func(b *sendParams) error { return deliverV3("API-KEY", b) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := sendInBatches(p, &bulkOptions{batchSize: 2}, deliver); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"b@example.com", "b@example.com"}; !reflect.DeepEqual(froms, expected) {
+		t.Errorf("Expected the random FROM addresses %v, got %v", expected, froms)
+	}
+
+	if _, err := withFromRotation(p.froms, "weighted", deliver); err == nil {
+		t.Error("Expected the unsupported rotation error")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Headers that SendGrid doesn't allow to be set as the custom headers
+var reservedHeaders = map[string]bool{
+	"x-sg-id":                   true,
+	"x-sg-eid":                  true,
+	"received":                  true,
+	"dkim-signature":            true,
+	"content-type":              true,
+	"content-transfer-encoding": true,
+	"to":                        true,
+	"from":                      true,
+	"subject":                   true,
+	"reply-to":                  true,
+	"cc":                        true,
+	"bcc":                       true,
+}
+
+// Header requesting TLS on the delivery to the recipient servers. SendGrid v3
+// API has no mail setting for it, so it is passed as the custom header.
+const requireTLSHeader = "X-Require-TLS"
+
+// Header overriding the message ID, eg, for the deduplication by the recipients
+const messageIDHeader = "Message-ID"
+
+// Message ID as per RFC 5322: "<left@right>" without spaces
+var messageIDFormat = regexp.MustCompile(`^<[^<>@\s]+@[^<>@\s]+>$`)
+
+// Validates the message ID adding the angle brackets if they are missing.
+func formatMessageID(raw string) (string, error) {
+	id := strings.TrimSpace(raw)
+	if !strings.HasPrefix(id, "<") {
+		id = "<" + id + ">"
+	}
+	if !messageIDFormat.MatchString(id) {
+		return "", fmt.Errorf("incorrect message ID %q, expected <unique-id@domain>", raw)
+	}
+	return id, nil
+}
+
+// Parses the custom header given as "Name: Value".
+func parseHeader(raw string) (name, value string, err error) {
+	parts := strings.SplitN(raw, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return "", "", fmt.Errorf("incorrect header %q, expected Name: Value", raw)
+	}
+	name, value = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if reservedHeaders[strings.ToLower(name)] {
+		return "", "", fmt.Errorf("the header %q is reserved and cannot be set", name)
+	}
+	return name, value, nil
+}
+
+// Reads the custom headers from a file with "Name: Value" lines.
+// Blank lines and lines starting with "#" are ignored.
+func readHeaders(filename string) ([]string, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var headers []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		headers = append(headers, line)
+	}
+	return headers, nil
+}
+
+// Parses the custom headers given as a JSON object or @FILENAME of the JSON
+// file into the "Name: Value" form.
+func parseHeadersJSON(raw string) ([]string, error) {
+	b := []byte(raw)
+	if strings.HasPrefix(raw, "@") {
+		var err error
+		if b, err = ioutil.ReadFile(raw[1:]); err != nil {
+			return nil, err
+		}
+	}
+	var values map[string]string
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("the headers should be a JSON object with string values: %v", err)
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := make([]string, len(names))
+	for i, name := range names {
+		headers[i] = name + ": " + values[name]
+	}
+	return headers, nil
+}
+
+// Parses and validates the custom headers. The header names are sent in
+// the exact case they are given. Since the header names are case-insensitive,
+// the later headers override the earlier ones with the same name in any case.
+func collectHeaders(raw []string) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(raw))
+	names := make(map[string]string, len(raw)) // the given names by the lowercased ones
+	for _, h := range raw {
+		name, value, err := parseHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		if given, ok := names[strings.ToLower(name)]; ok {
+			delete(headers, given)
+		}
+		names[strings.ToLower(name)] = name
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+// Returns the names of the response headers sorted, so that they get logged
+// in a stable order.
+func sortedHeaderNames(headers map[string][]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sendgrid/rest"
+)
+
+func TestParseHeader(t *testing.T) {
+	if name, value, err := parseHeader("X-Campaign:  spring "); err != nil || name != "X-Campaign" || value != "spring" {
+		t.Errorf("Unexpected header %q: %q (%v)", name, value, err)
+	}
+	for _, raw := range []string{"X-Campaign", ": value", "Subject: Hi", "dkim-signature: x"} {
+		if _, _, err := parseHeader(raw); err == nil {
+			t.Errorf("Expected an error for the header %q", raw)
+		}
+	}
+}
+
+func TestHeaderFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "headers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# campaign headers\nX-Campaign: spring\n\nX-Priority: 1\nX-Tag: file\n")
+	f.Close()
+
+	raw, err := readHeaders(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, err := collectHeaders(append(raw, "X-Tag: inline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		headers:          headers,
+	})
+	for name, value := range map[string]string{"X-Campaign": "spring", "X-Priority": "1", "X-Tag": "inline"} {
+		if message.Headers[name] != value {
+			t.Errorf("Expected the header %q to be %q, got %q", name, value, message.Headers[name])
+		}
+	}
+	if len(message.Headers) != 3 {
+		t.Errorf("Unexpected headers: %v", message.Headers)
+	}
+
+	f, _ = ioutil.TempFile("", "headers")
+	defer os.Remove(f.Name())
+	f.WriteString("X-Campaign: spring\nFrom: someone@example.com\n")
+	f.Close()
+	raw, _ = readHeaders(f.Name())
+	if _, err := collectHeaders(raw); err == nil {
+		t.Error("Expected the reserved header in the file to be rejected")
+	}
+}
+
+func TestParseHeadersJSON(t *testing.T) {
+	raw, err := parseHeadersJSON(`{"X-Foo": "bar", "X-Campaign": "spring"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, err := collectHeaders(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		headers:          headers,
+	})
+	if len(message.Headers) != 2 || message.Headers["X-Foo"] != "bar" || message.Headers["X-Campaign"] != "spring" {
+		t.Errorf("Unexpected headers: %v", message.Headers)
+	}
+
+	raw, err = parseHeadersJSON(`{"Reply-To": "someone@example.com"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collectHeaders(raw); err == nil {
+		t.Error("Expected the reserved header to be rejected")
+	}
+	if _, err := parseHeadersJSON(`["X-Foo"]`); err == nil {
+		t.Error("Expected an error for a JSON array")
+	}
+}
+
+func TestRequireTLSHeader(t *testing.T) {
+	headers, err := collectHeaders([]string{"X-Campaign: spring", requireTLSHeader + ": true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		headers:          headers,
+	})
+	if message.Headers["X-Require-TLS"] != "true" {
+		t.Errorf("Expected the X-Require-TLS header, got %v", message.Headers)
+	}
+}
+
+func TestHeaderCase(t *testing.T) {
+	headers, err := collectHeaders([]string{"x-my-header: file", "X-My-Header: inline", "X-lowercase-Tail: kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 2 || headers["X-My-Header"] != "inline" || headers["X-lowercase-Tail"] != "kept" {
+		t.Errorf("Expected the later header to override the earlier one in the given case, got %v", headers)
+	}
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		headers:          headers,
+	}
+
+	body, err := requestBody(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Headers map[string]string `json:"headers"`
+	}
+	json.Unmarshal(body, &decoded)
+	if decoded.Headers["X-My-Header"] != "inline" || decoded.Headers["X-lowercase-Tail"] != "kept" {
+		t.Errorf("Expected the header names in the given case in the v3 request: %s", body)
+	}
+
+	v2Headers, err := newV2Mail(p).HeadersString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(v2Headers, `"X-My-Header":"inline"`) || !strings.Contains(v2Headers, `"X-lowercase-Tail":"kept"`) {
+		t.Errorf("Expected the header names in the given case in the v2 request: %s", v2Headers)
+	}
+}
+
+func TestMessageIDHeader(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"<order-42@example.com>": "<order-42@example.com>",
+		" order-42@example.com ": "<order-42@example.com>",
+	} {
+		if id, err := formatMessageID(raw); err != nil || id != expected {
+			t.Errorf("Expected %q for %q, got %q (%v)", expected, raw, id, err)
+		}
+	}
+	for _, raw := range []string{"order-42", "<order 42@example.com>", "<a@b@c>", "<order-42@example.com"} {
+		if _, err := formatMessageID(raw); err == nil {
+			t.Errorf("Expected an error for %q", raw)
+		}
+	}
+
+	id, _ := formatMessageID("order-42@example.com")
+	headers, err := collectHeaders([]string{messageIDHeader + ": " + id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		headers:          headers,
+	})
+	if message.Headers["Message-ID"] != "<order-42@example.com>" {
+		t.Errorf("Expected the Message-ID header, got %v", message.Headers)
+	}
+}
+
+func TestLogResponseSortedHeaders(t *testing.T) {
+	defer func(v bool) { verbose = v }(verbose)
+	verbose = true
+	output := captureLog(func() {
+		logResponse(&rest.Response{StatusCode: 202, Headers: map[string][]string{
+			"X-Message-Id":   {"MESSAGE-ID"},
+			"Content-Length": {"0"},
+			"Date":           {"Sun, 01 Oct 2017 10:00:00 GMT"},
+			"Connection":     {"keep-alive"},
+		}})
+	})
+	last := -1
+	for _, name := range []string{"Connection", "Content-Length", "Date", "X-Message-Id"} {
+		i := strings.Index(output, name+": ")
+		if i < last {
+			t.Fatalf("Expected the headers in the sorted order, got:\n%s", output)
+		}
+		last = i
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	styleBlock   = regexp.MustCompile(`(?is)<style\b[^>]*>(.*?)</style>`)
+	cssComment   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	simpleCSS    = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*)?((?:[.#][A-Za-z0-9_-]+)*)$`)
+	cssQualifier = regexp.MustCompile(`[.#][^.#]+`)
+	startTag     = regexp.MustCompile(`(?i)<([a-z][a-z0-9]*)\b((?:[^>"']|"[^"]*"|'[^']*')*?)(/?)>`)
+	tagAttribute = regexp.MustCompile(`(?is)\s([a-z-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+)
+
+// Elements that are never styled
+var unstyledElements = map[string]bool{
+	"html": true, "head": true, "title": true, "meta": true, "link": true, "style": true, "script": true, "base": true,
+}
+
+// CSS rule with a simple selector: an element name, classes and an ID
+type cssRule struct {
+	element      string
+	classes, ids []string
+	declarations []string
+	specificity  int
+	order        int
+}
+
+// Checks if the rule applies to the element with the class and ID attributes.
+func (r *cssRule) matches(element string, classes map[string]bool, id string) bool {
+	if r.element != "" && !strings.EqualFold(r.element, element) {
+		return false
+	}
+	for _, c := range r.classes {
+		if !classes[c] {
+			return false
+		}
+	}
+	for _, i := range r.ids {
+		if i != id {
+			return false
+		}
+	}
+	return true
+}
+
+// Splits the style sheet into the rules with the simple selectors and the
+// remaining CSS, eg, the @media rules, the pseudo-classes and the
+// combinators, that cannot be inlined.
+func parseStyleSheet(css string, rules []*cssRule) ([]*cssRule, string) {
+	css = cssComment.ReplaceAllString(css, "")
+	var remaining []string
+	for {
+		open := strings.Index(css, "{")
+		if open < 0 {
+			break
+		}
+		selectors := strings.TrimSpace(css[:open])
+		end := open + 1
+		for depth := 1; end < len(css) && depth > 0; end++ {
+			switch css[end] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+		}
+		body := strings.TrimSpace(strings.TrimSuffix(css[open+1:end], "}"))
+		css = css[end:]
+		if strings.HasPrefix(selectors, "@") {
+			remaining = append(remaining, selectors+" {"+body+"}")
+			continue
+		}
+		var declarations []string
+		for _, d := range strings.Split(body, ";") {
+			if d = strings.TrimSpace(d); d != "" {
+				declarations = append(declarations, strings.Replace(d, `"`, "'", -1))
+			}
+		}
+		var kept []string
+		for _, selector := range strings.Split(selectors, ",") {
+			selector = strings.TrimSpace(selector)
+			m := simpleCSS.FindStringSubmatch(selector)
+			if selector == "" || m == nil {
+				kept = append(kept, selector)
+				continue
+			}
+			r := &cssRule{element: m[1], declarations: declarations, order: len(rules)}
+			for _, part := range cssQualifier.FindAllString(m[2], -1) {
+				if part[0] == '.' {
+					r.classes = append(r.classes, part[1:])
+				} else {
+					r.ids = append(r.ids, part[1:])
+				}
+			}
+			r.specificity = len(r.ids)*10000 + len(r.classes)*100
+			if r.element != "" {
+				r.specificity++
+			}
+			rules = append(rules, r)
+		}
+		if len(kept) > 0 {
+			remaining = append(remaining, strings.Join(kept, ", ")+" {"+body+"}")
+		}
+	}
+	return rules, strings.Join(remaining, "\n")
+}
+
+// Moves the rules of the <style> blocks to the style attributes of the
+// matching elements. The existing style attributes take precedence, the
+// rules that cannot be inlined are kept in the <style> blocks.
+func inlineCSS(html string) string {
+	var rules []*cssRule
+	html = styleBlock.ReplaceAllStringFunc(html, func(block string) string {
+		var remaining string
+		rules, remaining = parseStyleSheet(styleBlock.FindStringSubmatch(block)[1], rules)
+		if remaining == "" {
+			return ""
+		}
+		return "<style>\n" + remaining + "\n</style>"
+	})
+	if len(rules) == 0 {
+		return html
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].specificity < rules[j].specificity })
+
+	return startTag.ReplaceAllStringFunc(html, func(tag string) string {
+		m := startTag.FindStringSubmatch(tag)
+		element, attributes := strings.ToLower(m[1]), m[2]
+		if unstyledElements[element] {
+			return tag
+		}
+		classes, id, style := make(map[string]bool), "", ""
+		for _, a := range tagAttribute.FindAllStringSubmatch(attributes, -1) {
+			value := strings.Trim(a[2], `"'`)
+			switch strings.ToLower(a[1]) {
+			case "class":
+				for _, c := range strings.Fields(value) {
+					classes[c] = true
+				}
+			case "id":
+				id = value
+			case "style":
+				style = strings.TrimSpace(value)
+			}
+		}
+		var declarations []string
+		for _, r := range rules {
+			if r.matches(element, classes, id) {
+				declarations = append(declarations, r.declarations...)
+			}
+		}
+		if len(declarations) == 0 {
+			return tag
+		}
+		if style != "" {
+			declarations = append(declarations, strings.TrimSuffix(style, ";"))
+		}
+		attributes = tagAttribute.ReplaceAllStringFunc(attributes, func(a string) string {
+			if strings.EqualFold(tagAttribute.FindStringSubmatch(a)[1], "style") {
+				return ""
+			}
+			return a
+		})
+		return "<" + m[1] + attributes + ` style="` + strings.Join(declarations, "; ") + `"` + m[3] + ">"
+	})
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import "testing"
+
+func TestInlineCSS(t *testing.T) {
+	html := `<html><head><style type="text/css">
+/* the brand colours */
+p { color: #333; margin: 0 }
+.note, #footer { font-family: "Helvetica", sans-serif; }
+p.note { color: red; }
+a:hover { color: blue; }
+@media (max-width: 600px) { p { font-size: 12px; } }
+</style></head>
+<body><p>Text</p><p class="note highlighted" style="margin: 4px">Note</p><div id='footer'>Footer</div><br/></body></html>`
+	expected := `<html><head><style>
+a:hover {color: blue;}
+@media (max-width: 600px) {p { font-size: 12px; }}
+</style></head>
+<body><p style="color: #333; margin: 0">Text</p>` +
+		`<p class="note highlighted" style="color: #333; margin: 0; font-family: 'Helvetica', sans-serif; color: red; margin: 4px">Note</p>` +
+		`<div id='footer' style="font-family: 'Helvetica', sans-serif">Footer</div><br/></body></html>`
+	if inlined := inlineCSS(html); inlined != expected {
+		t.Errorf("Expected the inlined HTML:\n%s\ngot:\n%s", expected, inlined)
+	}
+
+	if inlined := inlineCSS("<style>td { padding: 2px }</style><table><tr><td>1</td></tr></table>"); inlined !=
+		`<table><tr><td style="padding: 2px">1</td></tr></table>` {
+		t.Errorf("Expected the style block to be removed once inlined, got %s", inlined)
+	}
+	if plain := "<p>No styles</p>"; inlineCSS(plain) != plain {
+		t.Errorf("Expected the HTML without the style blocks intact, got %s", inlineCSS(plain))
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var imgSrc = regexp.MustCompile(`(?i)(<img\b[^>]*?\bsrc\s*=\s*)("([^"]*)"|'([^']*)')`)
+
+// Checks if the image source refers to a local file rather than URL or CID.
+func isLocalImage(src string) bool {
+	return src != "" && !strings.Contains(src, ":") && !strings.HasPrefix(src, "//")
+}
+
+// Strategies of the CID generation for the inlined images
+const (
+	cidIndex    = "index"    // image1@sendgrid-cli, image2@sendgrid-cli, ...
+	cidFilename = "filename" // the base name of the image file, eg, logo.png@sendgrid-cli
+	cidUUID     = "uuid"     // random UUID, collision-free across the messages
+)
+
+var cidUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Returns the CID generator of the strategy. The generated CIDs are unique
+// within the message.
+func cidGenerator(strategy string) (func(filename string) string, error) {
+	used := make(map[string]bool)
+	unique := func(id string) string {
+		cid := id + "@sendgrid-cli"
+		for n := 2; used[cid]; n++ {
+			cid = fmt.Sprintf("%s-%d@sendgrid-cli", id, n)
+		}
+		used[cid] = true
+		return cid
+	}
+	switch strategy {
+	case cidIndex:
+		return func(string) string { return unique(fmt.Sprintf("image%d", len(used)+1)) }, nil
+	case cidFilename:
+		return func(filename string) string { return unique(cidUnsafe.ReplaceAllString(filepath.Base(filename), "_")) }, nil
+	case cidUUID:
+		return func(string) string {
+			b := make([]byte, 16)
+			rand.Read(b)
+			b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80 // version 4, RFC 4122 variant
+			return unique(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]))
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported CID strategy %q, use filename, uuid or index", strategy)
+}
+
+// Replaces the local image references in the HTML body with the CID references
+// to the inline attachments created from the image files. The relative paths
+// are resolved against baseDir. The images larger than the threshold (in bytes)
+// are left intact. The CIDs are generated with the strategy (see cidGenerator).
+func autoInlineImages(html, baseDir string, threshold int64, strategy string) (string, []*inlineAttachment, error) {
+	newCID, err := cidGenerator(strategy)
+	if err != nil {
+		return "", nil, err
+	}
+	var attachments []*inlineAttachment
+	cids := make(map[string]string)
+	html = imgSrc.ReplaceAllStringFunc(html, func(m string) string {
+		parts := imgSrc.FindStringSubmatch(m)
+		src := parts[3] + parts[4]
+		if err != nil || !isLocalImage(src) {
+			return m
+		}
+		filename := src
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(baseDir, filename)
+		}
+		cid, ok := cids[filename]
+		if !ok {
+			fi, e := os.Stat(filename)
+			if e != nil {
+				err = fmt.Errorf("failed to inline the image %q: %v", src, e)
+				return m
+			}
+			if fi.Size() > threshold {
+				log.Infof("The image %q (%d bytes) exceeds the auto-inline threshold, leaving it intact.", src, fi.Size())
+				return m
+			}
+			content, e := ioutil.ReadFile(filename)
+			if e != nil {
+				err = fmt.Errorf("failed to inline the image %q: %v", src, e)
+				return m
+			}
+			cid = newCID(filename)
+			cids[filename] = cid
+			attachments = append(attachments, &inlineAttachment{
+				name:        filepath.Base(filename),
+				contentType: attachmentType(filename),
+				content:     content,
+				contentID:   cid,
+			})
+		}
+		return parts[1] + `"cid:` + cid + `"`
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return html, attachments, nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAutoInlineImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "logo.png"), []byte("PNG"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "large.png"), make([]byte, 2048), 0644)
+
+	html, attachments, err := autoInlineImages(`<p><img alt="Logo" src="logo.png"></p>
+<img src='large.png'><img src="https://example.com/remote.png"><img src="logo.png">`, dir, 1024, cidIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(html, `src="cid:image1@sendgrid-cli"`) != 2 {
+		t.Errorf("Expected the local image references to be rewritten, got:\n%s", html)
+	}
+	if !strings.Contains(html, `src='large.png'`) || !strings.Contains(html, `src="https://example.com/remote.png"`) {
+		t.Errorf("Expected the large and remote images to be left intact, got:\n%s", html)
+	}
+	if len(attachments) != 1 || attachments[0].name != "logo.png" || attachments[0].contentID != "image1@sendgrid-cli" ||
+		string(attachments[0].content) != "PNG" || attachments[0].contentType != "image/png" {
+		t.Fatalf("Unexpected inline attachments: %+v", attachments)
+	}
+
+	message := newV3Message(&sendParams{
+		from:        "sender@example.com",
+		tos:         []string{"to@example.com"},
+		subject:     "Test",
+		htmlContent: html,
+		inlineAtts:  attachments,
+	})
+	if a := message.Attachments[0]; a.Disposition != "inline" || a.ContentID != "image1@sendgrid-cli" {
+		t.Errorf("Expected the inline attachment with CID, got %+v", a)
+	}
+
+	if _, _, err := autoInlineImages(`<img src="missing.png">`, dir, 1024, cidIndex); err == nil {
+		t.Error("Expected an error for the missing image")
+	}
+}
+
+func TestCIDStrategy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, sub := range []string{"header", "footer"} {
+		os.Mkdir(filepath.Join(dir, sub), 0755)
+		ioutil.WriteFile(filepath.Join(dir, sub, "logo.png"), []byte(sub), 0644)
+	}
+	html := `<img src="header/logo.png"><img src="footer/logo.png">`
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}@sendgrid-cli$`)
+	for strategy, valid := range map[string]func(i int, cid string) bool{
+		cidUUID:  func(i int, cid string) bool { return uuid.MatchString(cid) },
+		cidIndex: func(i int, cid string) bool { return cid == []string{"image1@sendgrid-cli", "image2@sendgrid-cli"}[i] },
+		cidFilename: func(i int, cid string) bool {
+			return cid == []string{"logo.png@sendgrid-cli", "logo.png-2@sendgrid-cli"}[i]
+		},
+	} {
+		rewritten, attachments, err := autoInlineImages(html, dir, 1024, strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attachments) != 2 || attachments[0].contentID == attachments[1].contentID {
+			t.Fatalf("Expected 2 attachments with unique CIDs under the %s strategy, got %+v", strategy, attachments)
+		}
+		for i, a := range attachments {
+			if !valid(i, a.contentID) || !strings.Contains(rewritten, `"cid:`+a.contentID+`"`) {
+				t.Errorf("Unexpected CID %q under the %s strategy in:\n%s", a.contentID, strategy, rewritten)
+			}
+		}
+	}
+	if _, _, err := autoInlineImages(html, dir, 1024, "random"); err == nil {
+		t.Error("Expected an error for the unsupported strategy")
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the result
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// Returns the output format given with --output or the deprecated --json.
+func outputFormat(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("output") {
+		format := strings.ToLower(flagString(cmd, "output"))
+		if format != outputTable && format != outputJSON && format != outputCSV {
+			failf(exitUsage, "Unsupported output format %q, use table, json or csv.", format)
+		}
+		return format
+	}
+	if flagBool(cmd, "json") {
+		return outputJSON
+	}
+	return outputTable
+}
+
+// Prints the result in the output format: the value v as JSON or the rows
+// under the header as a table or CSV.
+func printOutput(cmd *cobra.Command, v interface{}, header []string, rows [][]string) error {
+	switch outputFormat(cmd) {
+	case outputJSON:
+		return printJSON(stdout, v)
+	case outputCSV:
+		return printCSV(stdout, header, rows)
+	}
+	return printTable(stdout, header, rows)
+}
+
+// Prints the value as JSON, indented with two spaces if --pretty is given.
+func printJSON(w io.Writer, v interface{}) error {
+	var b []byte
+	var err error
+	if prettyJSON {
+		b, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// Prints the rows as a table aligned by columns with the header row on top.
+func printTable(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Prints the rows as CSV with the header row on top.
+func printCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	cw.WriteAll(rows)
+	return cw.Error()
+}
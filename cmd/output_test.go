@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintJSONPretty(t *testing.T) {
+	defer func(p bool) { prettyJSON = p }(prettyJSON)
+	v := map[string]interface{}{"id": "T-1", "versions": []string{"v1"}}
+
+	var out bytes.Buffer
+	prettyJSON = false
+	printJSON(&out, v)
+	if expected := `{"id":"T-1","versions":["v1"]}` + "\n"; out.String() != expected {
+		t.Errorf("Expected compact JSON %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	prettyJSON = true
+	printJSON(&out, v)
+	expected := "{\n  \"id\": \"T-1\",\n  \"versions\": [\n    \"v1\"\n  ]\n}\n"
+	if out.String() != expected {
+		t.Errorf("Expected JSON indented with two spaces:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
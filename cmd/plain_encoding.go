@@ -0,0 +1,83 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Plain-text encodings given with --plain-encoding
+const (
+	plainEncodingNone            = "none"
+	plainEncodingQuotedPrintable = "quoted-printable"
+	plainEncodingBase64          = "base64"
+)
+
+const (
+	// Maximum line length of the quoted-printable content (RFC 2045)
+	quotedPrintableLineLength = 76
+	// Maximum line length of the message (RFC 5322)
+	maxLineLength = 998
+)
+
+// Prepares the plain-text content for the encoding. SendGrid picks the
+// transfer encoding itself, so the content is only made safe for it: the line
+// endings get normalized to CRLF and, for quoted-printable, the long lines get
+// wrapped at the word boundaries.
+func preparePlainText(content, encoding string) (string, error) {
+	switch encoding {
+	case plainEncodingNone:
+		return content, nil
+	case plainEncodingQuotedPrintable, plainEncodingBase64:
+	default:
+		return "", fmt.Errorf("unsupported plain-text encoding %q, use %s, %s or %s", encoding,
+			plainEncodingQuotedPrintable, plainEncodingBase64, plainEncodingNone)
+	}
+	lines := strings.Split(strings.Replace(strings.Replace(content, "\r\n", "\n", -1), "\r", "\n", -1), "\n")
+	width := maxLineLength
+	if encoding == plainEncodingQuotedPrintable {
+		width = quotedPrintableLineLength
+	}
+	var wrapped []string
+	for _, line := range lines {
+		wrapped = append(wrapped, wrapLine(line, width)...)
+	}
+	return strings.Join(wrapped, "\r\n"), nil
+}
+
+// Wraps the line at the spaces so that the lines are at most width bytes
+// long. The words longer than the width are split only at the RFC 5322
+// line length limit.
+func wrapLine(line string, width int) []string {
+	var lines []string
+	for len(line) > width {
+		i := strings.LastIndexByte(line[:width+1], ' ')
+		if i <= 0 { // the first word is longer than the width
+			if i = strings.IndexByte(line[1:], ' ') + 1; i <= 0 || i > maxLineLength {
+				if len(line) <= maxLineLength {
+					break
+				}
+				for i = maxLineLength; !utf8.RuneStart(line[i]); i-- {
+				}
+				lines, line = append(lines, line[:i]), line[i:]
+				continue
+			}
+		}
+		lines, line = append(lines, line[:i]), line[i+1:]
+	}
+	return append(lines, line)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreparePlainText(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 20)
+	content := "Hello,\r\n" + long + "\nBye\r"
+
+	if prepared, err := preparePlainText(content, plainEncodingNone); err != nil || prepared != content {
+		t.Errorf("Expected the content intact without the encoding, got %q (%v)", prepared, err)
+	}
+
+	prepared, err := preparePlainText(content, plainEncodingQuotedPrintable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(prepared, "\r\n")
+	if len(lines) < 5 || lines[0] != "Hello," || lines[len(lines)-2] != "Bye" {
+		t.Fatalf("Expected the wrapped lines with CRLF line endings, got %q", prepared)
+	}
+	for _, line := range lines {
+		if len(line) > quotedPrintableLineLength {
+			t.Errorf("Expected the lines of at most %d bytes, got %q", quotedPrintableLineLength, line)
+		}
+	}
+	if joined := strings.Join(lines[1:len(lines)-2], " "); joined != long {
+		t.Errorf("Expected only the spaces to be replaced with the line breaks, got %q", joined)
+	}
+
+	if prepared, _ := preparePlainText(content, plainEncodingBase64); prepared != "Hello,\r\n"+long+"\r\nBye\r\n" {
+		t.Errorf("Expected only the line endings to be normalized for base64, got %q", prepared)
+	}
+	if _, err := preparePlainText(content, "uuencode"); err == nil {
+		t.Error("Expected an error for the unsupported encoding")
+	}
+}
+
+func TestWrapLongWord(t *testing.T) {
+	word := strings.Repeat("ü", 600) // 1200 bytes
+	lines := wrapLine("see "+word+" here", quotedPrintableLineLength)
+	if len(lines) != 4 || lines[0] != "see" || lines[3] != "here" {
+		t.Fatalf("Unexpected lines: %d", len(lines))
+	}
+	if len(lines[1]) != maxLineLength || lines[1]+lines[2] != word {
+		t.Errorf("Expected the long word to be split at the line length limit, got %d and %d bytes",
+			len(lines[1]), len(lines[2]))
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// previewCmd represents the preview command
+var previewCmd = &cobra.Command{
+	Use:   "preview [BODY]",
+	Short: "Print the message body without sending",
+	Long: `Prints the HTML body given with --html, --body or as the argument to the
+standard output without sending. With --plain prints the plain-text version
+converted from the HTML body, revealing the conversion problems early, eg,
+
+sendgrid-cli preview --plain --html newsletter.html
+`,
+	Run: preview,
+}
+
+func init() {
+	RootCmd.AddCommand(previewCmd)
+	previewCmd.Flags().Bool("plain", false, "Print the plain-text conversion of the HTML body.")
+}
+
+func preview(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	var htmlContent string
+	if bodyFilename := flagString(cmd, "body"); bodyFilename != "" {
+		source := readFile(bodyFilename, flagBool(cmd, "gzip"), flagBool(cmd, "trim"))
+		var err error
+		if htmlContent, _, err = renderBody(source, flagString(cmd, "render")); err != nil {
+			fail(exitUsage, err)
+		}
+	} else {
+		htmlContent, _ = resolveContent(&contentSources{
+			htmlFilename: flagString(cmd, "html"),
+			gzip:         flagBool(cmd, "gzip"),
+			trim:         flagBool(cmd, "trim"),
+			args:         args,
+		})
+	}
+	output, err := previewBody(htmlContent, flagBool(cmd, "plain"))
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	fmt.Fprintln(stdout, output)
+}
+
+// Returns the HTML body or its plain-text conversion.
+func previewBody(htmlContent string, plain bool) (string, error) {
+	if htmlContent == "" {
+		return "", errors.New("the message has no HTML body to preview")
+	}
+	if !plain {
+		return htmlContent, nil
+	}
+	text, err := htmlToText(htmlContent)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert the HTML body into plain-text: %v", err)
+	}
+	return text, nil
+}
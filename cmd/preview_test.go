@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/jaytaylor/html2text"
+)
+
+func TestPreviewPlain(t *testing.T) {
+	html := `<h1>News</h1><p>Read <a href="https://example.com/post">the post</a>.</p>` +
+		`<table><tr><th>Item</th><th>Price</th></tr><tr><td>Tea</td><td>3</td></tr></table>`
+	expected, err := html2text.FromString(html, html2text.Options{PrettyTables: prettyTables})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(w io.Writer) { stdout = w }(stdout)
+	var out bytes.Buffer
+	stdout = &out
+	defer previewCmd.Flags().Set("plain", "false")
+	RootCmd.SetArgs([]string{"preview", "--plain", html})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected+"\n" {
+		t.Errorf("Expected the plain-text conversion:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	if _, err := previewBody("", true); err == nil {
+		t.Error("Expected the missing HTML body error")
+	}
+	if b, _ := previewBody(html, false); b != html {
+		t.Errorf("Expected the HTML body, got %q", b)
+	}
+}
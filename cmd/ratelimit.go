@@ -0,0 +1,72 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Tracks the SendGrid API rate limit reported in the response headers
+type rateLimiter struct {
+	remaining int       // calls left in the current window, -1 if unknown
+	reset     time.Time // end of the current window
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{remaining: -1}
+}
+
+// Records the X-RateLimit-Remaining and X-RateLimit-Reset (UNIX time)
+// headers of the API response.
+func (l *rateLimiter) observe(headers map[string][]string) {
+	h := http.Header(headers)
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	l.remaining = remaining
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		l.reset = time.Unix(reset, 0)
+	}
+}
+
+// Pauses until the reset of the window if no calls are left in it or the
+// context gets cancelled.
+func (l *rateLimiter) wait(ctx context.Context) {
+	if l.remaining != 0 {
+		return
+	}
+	if d := l.reset.Sub(now()); d > 0 {
+		log.Infof("The API rate limit is reached, pausing for %v until it resets...", d)
+		sleep(ctx, d)
+	}
+	l.remaining = -1
+}
+
+// Wraps the delivery pausing before the API calls exceeding the rate limit.
+func (l *rateLimiter) wrap(ctx context.Context, deliver func(*sendParams) error) func(*sendParams) error {
+	return func(p *sendParams) error {
+		l.wait(ctx)
+		if ctx.Err() != nil {
+			return errInterrupted
+		}
+		return deliver(p)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sendgrid/rest"
+)
+
+func TestRateLimitAware(t *testing.T) {
+	started := time.Date(2017, 10, 1, 10, 0, 0, 0, time.UTC)
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return started }
+	var sleeps []time.Duration
+	defer func(s func(context.Context, time.Duration)) { sleep = s }(sleep)
+	sleep = func(ctx context.Context, d time.Duration) { sleeps = append(sleeps, d) }
+	defer func(q bool) { quiet = q }(quiet)
+	quiet = true
+
+	remaining := 2
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if remaining < 0 {
+			t.Error("The API call was made beyond the rate limit")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		remaining--
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(started.Add(30*time.Second).Unix(), 10))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	limiter := newRateLimiter()
+	defer func(o func(*rest.Response)) { observeResponse = o }(observeResponse)
+	observeResponse = func(response *rest.Response) {
+		limiter.observe(response.Headers)
+		if limiter.remaining == 0 {
+			remaining = 2 // the window resets during the pause
+		}
+	}
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	ctx := context.Background()
+	deliver := limiter.wrap(ctx, func(b *sendParams) error { return deliverV3("API-KEY", b) })
+	if err := sendInBatches(p, &bulkOptions{batchSize: 1}, deliver); err != nil {
+		t.Fatal(err)
+	}
+	if len(sleeps) != 1 || sleeps[0] != 30*time.Second {
+		t.Errorf("Expected a single pause of 30s until the rate limit resets, got %v", sleeps)
+	}
+}
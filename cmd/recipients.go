@@ -0,0 +1,344 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Recipient read from the recipients file
+type recipient struct {
+	address string // "Full Name <name@domain.name>" OR "name@domain.name"
+	replyTo string
+	subject string // overrides the message subject
+	sendAt  string // schedules the recipient's personalization
+	subuser string // subuser on behalf of which the message to the recipient is sent
+}
+
+// Returns the recipients read from the CSV file with the mapped column headers.
+func readRecipients(filename string, columnMap map[string]string) ([]recipient, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: the recipients file is empty", filename)
+	}
+	columns := make(map[string]int)
+	for i, h := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	header := func(name string) string {
+		if h, ok := columnMap[name]; ok {
+			return strings.ToLower(h)
+		}
+		return name
+	}
+	if _, ok := columns[header("email")]; !ok {
+		return nil, fmt.Errorf("%s: missing the %q column", filename, header("email"))
+	}
+	column := func(row []string, name string) string {
+		if i, ok := columns[header(name)]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	recipients := make([]recipient, 0, len(records)-1)
+	for n, row := range records[1:] {
+		email := column(row, "email")
+		if email == "" {
+			return nil, fmt.Errorf("%s:%d: missing the email address", filename, n+2)
+		}
+		r := recipient{
+			address: email,
+			replyTo: column(row, "reply_to"),
+			subject: column(row, "subject"),
+			sendAt:  column(row, "send_at"),
+			subuser: column(row, "subuser"),
+		}
+		if name := column(row, "name"); name != "" {
+			r.address = name + " <" + email + ">"
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+// Columns of the recipients file
+var recipientColumns = map[string]bool{
+	"email": true, "name": true, "reply_to": true, "subject": true, "send_at": true, "subuser": true,
+}
+
+// Parses the mapping of the recipients file columns to the CSV headers given
+// as "column=Header" pairs separated by commas, eg, "email=Email,name=FullName".
+func parseColumnMap(raw string) (map[string]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	columnMap := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		column := strings.ToLower(strings.TrimSpace(parts[0]))
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("incorrect column mapping %q, expected column=Header", pair)
+		}
+		if !recipientColumns[column] {
+			return nil, fmt.Errorf("unknown column %q, expected email, name, reply_to, subject, send_at or subuser", column)
+		}
+		columnMap[column] = strings.TrimSpace(parts[1])
+	}
+	return columnMap, nil
+}
+
+// Resolves the reply-to address of the message from the recipients' reply-to
+// addresses. SendGrid supports only the message-level reply-to, so all the
+// given values have to be the same.
+func recipientsReplyTo(replyTo string, recipients []recipient) (string, error) {
+	for _, r := range recipients {
+		if r.replyTo == "" {
+			continue
+		}
+		if replyTo == "" {
+			replyTo = r.replyTo
+		} else if !strings.EqualFold(r.replyTo, replyTo) {
+			return "", fmt.Errorf(
+				"the reply-to %q of %q differs from %q: SendGrid supports only a single reply-to per message",
+				r.replyTo, r.address, replyTo)
+		}
+	}
+	return replyTo, nil
+}
+
+// Returns the warnings about the reply-to addresses that are also the TO, CC
+// or BCC recipients: the replies would go back to the recipients, possibly
+// looping with their auto-responders.
+func replyToRecipientWarnings(replyTos, tos, ccs, bccs []string) []string {
+	lists := make(map[string]string)
+	for _, l := range []struct {
+		name      string
+		addresses []string
+	}{{"BCC", bccs}, {"CC", ccs}, {"TO", tos}} {
+		for _, raw := range l.addresses {
+			lists[strings.ToLower(createAddress(raw).Address)] = l.name
+		}
+	}
+	var warnings []string
+	for _, raw := range replyTos {
+		if list, ok := lists[strings.ToLower(createAddress(raw).Address)]; ok {
+			warnings = append(warnings, fmt.Sprintf(
+				"The reply-to address %s is also a %s recipient, the replies may loop back to it", raw, list))
+		}
+	}
+	return warnings
+}
+
+// Merges the reply-to address with the addresses read from the reply-to file
+// dropping the duplicates. A single address is returned as the reply-to
+// address, multiple ones - as the reply-to list.
+func mergeReplyTos(replyTo string, addresses []string) (string, []string, error) {
+	if replyTo != "" {
+		addresses = append([]string{replyTo}, addresses...)
+	}
+	var list []string
+	seen := make(map[string]bool)
+	for _, raw := range addresses {
+		address := strings.ToLower(createAddress(raw).Address)
+		if !strings.Contains(address, "@") {
+			return "", nil, fmt.Errorf("the reply-to address %q is incorrect", raw)
+		}
+		if !seen[address] {
+			seen[address] = true
+			list = append(list, raw)
+		}
+	}
+	switch len(list) {
+	case 0:
+		return "", nil, nil
+	case 1:
+		return list[0], nil, nil
+	}
+	return "", list, nil
+}
+
+// Collects the per-recipient subjects from the recipients and the subjects
+// given as "address=subject". The subjects are keyed by the normalized address.
+func recipientSubjects(recipients []recipient, raw []string) (map[string]string, error) {
+	subjects := make(map[string]string)
+	for _, r := range recipients {
+		if r.subject != "" {
+			subjects[createAddress(r.address).Address] = r.subject
+		}
+	}
+	for _, s := range raw {
+		parts := strings.SplitN(s, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("incorrect personalization subject %q, expected address=subject", s)
+		}
+		subjects[createAddress(parts[0]).Address] = parts[1]
+	}
+	if len(subjects) == 0 {
+		return nil, nil
+	}
+	return subjects, nil
+}
+
+// SendGrid subuser name
+var subuserName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@+-]{0,63}$`)
+
+// Collects the per-recipient subusers keyed by the normalized address.
+func recipientSubusers(recipients []recipient) (map[string]string, error) {
+	subusers := make(map[string]string)
+	for _, r := range recipients {
+		if r.subuser == "" {
+			continue
+		}
+		if !subuserName.MatchString(r.subuser) {
+			return nil, fmt.Errorf("%s: incorrect subuser name %q", r.address, r.subuser)
+		}
+		subusers[createAddress(r.address).Address] = r.subuser
+	}
+	if len(subusers) == 0 {
+		return nil, nil
+	}
+	return subusers, nil
+}
+
+// Pipes the recipients, one per line, through the shell command and returns
+// the recipients printed by the command. The command failure aborts the send.
+func filterRecipients(command string, tos []string) ([]string, error) {
+	c := exec.Command("sh", "-c", command)
+	c.Stdin = strings.NewReader(strings.Join(tos, "\n") + "\n")
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("the recipient filter %q failed: %v %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	var filtered []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered, nil
+}
+
+// Current time (can be replaced for testing)
+var now = time.Now
+
+// How far ahead the messages can be scheduled, SendGrid allows 72 hours
+// by default (set with --max-send-at-window)
+var maxSendAtWindow = 72 * time.Hour
+
+// Parses the time given either as a UNIX timestamp or in RFC 3339 format,
+// eg, 2017-10-01T10:00:00Z.
+func parseSendTime(raw string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		seconds, e := strconv.ParseInt(raw, 10, 64)
+		if e != nil {
+			return t, fmt.Errorf("incorrect send time %q, expected UNIX timestamp or RFC 3339 time", raw)
+		}
+		t = time.Unix(seconds, 0)
+	}
+	return t, nil
+}
+
+// Parses the scheduled delivery time (see parseSendTime) rejecting the times
+// beyond the scheduling window.
+func parseSendAt(raw string) (int, error) {
+	t, err := parseSendTime(raw)
+	if err != nil {
+		return 0, err
+	}
+	if t.After(now().Add(maxSendAtWindow)) {
+		return 0, fmt.Errorf("the send time %q is more than %v ahead (see --max-send-at-window)", raw, maxSendAtWindow)
+	}
+	return int(t.Unix()), nil
+}
+
+// Collects the per-recipient send times keyed by the normalized address.
+func recipientSendAts(recipients []recipient) (map[string]int, error) {
+	sendAts := make(map[string]int)
+	for _, r := range recipients {
+		if r.sendAt == "" {
+			continue
+		}
+		sendAt, err := parseSendAt(r.sendAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", r.address, err)
+		}
+		sendAts[createAddress(r.address).Address] = sendAt
+	}
+	if len(sendAts) == 0 {
+		return nil, nil
+	}
+	return sendAts, nil
+}
+
+// Reads the addresses from a file, one per line. Blank lines and lines
+// starting with "#" are ignored.
+func readAddresses(filename string) ([]string, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var addresses []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			addresses = append(addresses, line)
+		}
+	}
+	return addresses, nil
+}
+
+// Removes the addresses already present in the higher-priority lists
+// (TO > CC > BCC) from the lower-priority ones.
+func dedupeAcrossLists(tos, ccs, bccs []string) ([]string, []string) {
+	seen := make(map[string]string)
+	for _, to := range tos {
+		seen[strings.ToLower(createAddress(to).Address)] = "TO"
+	}
+	dedupe := func(list []string, name string) []string {
+		var kept []string
+		for _, raw := range list {
+			address := strings.ToLower(createAddress(raw).Address)
+			if first, ok := seen[address]; ok {
+				log.Infof("Removed %s from the %s list, it's already in the %s list.", raw, name, first)
+				continue
+			}
+			seen[address] = name
+			kept = append(kept, raw)
+		}
+		return kept
+	}
+	return dedupe(ccs, "CC"), dedupe(bccs, "BCC")
+}
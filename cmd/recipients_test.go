@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Writes the content into a temporary file and returns its name.
+func tempFile(t *testing.T, content string) string {
+	t.Helper()
+	f, err := ioutil.TempFile("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestReadRecipients(t *testing.T) {
+	filename := tempFile(t, "Email,Name,Reply_To\na@example.com,Alice,help@example.com\nb@example.com,,\n")
+	defer os.Remove(filename)
+
+	recipients, err := readRecipients(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != 2 || recipients[0].address != "Alice <a@example.com>" ||
+		recipients[0].replyTo != "help@example.com" || recipients[1].address != "b@example.com" {
+		t.Errorf("Unexpected recipients: %+v", recipients)
+	}
+
+	filename = tempFile(t, "name\nAlice\n")
+	defer os.Remove(filename)
+	if _, err := readRecipients(filename, nil); err == nil {
+		t.Error("Expected an error for the missing email column")
+	}
+}
+
+func TestReadRecipientsColumnMap(t *testing.T) {
+	filename := tempFile(t, "FullName,E-mail Address,Subject\n"+
+		"John Doe,john@example.com,Hi John\n,jane@example.com,\n")
+	defer os.Remove(filename)
+
+	columnMap, err := parseColumnMap("email=E-mail Address, name=fullname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipients, err := readRecipients(filename, columnMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != 2 || recipients[0].address != "John Doe <john@example.com>" ||
+		recipients[0].subject != "Hi John" || recipients[1].address != "jane@example.com" {
+		t.Errorf("Unexpected recipients: %+v", recipients)
+	}
+	if _, err := readRecipients(filename, nil); err == nil {
+		t.Error("Expected an error for the unmapped email column")
+	}
+	for _, raw := range []string{"email", "email=", "phone=Phone"} {
+		if _, err := parseColumnMap(raw); err == nil {
+			t.Errorf("Expected an error for the mapping %q", raw)
+		}
+	}
+}
+
+func TestRecipientsReplyTo(t *testing.T) {
+	replyTo, err := recipientsReplyTo("", []recipient{
+		{address: "a@example.com", replyTo: "help@example.com"},
+		{address: "b@example.com"},
+		{address: "c@example.com", replyTo: "help@example.com"},
+	})
+	if err != nil || replyTo != "help@example.com" {
+		t.Errorf("Expected the common reply-to, got %q (%v)", replyTo, err)
+	}
+
+	_, err = recipientsReplyTo("", []recipient{
+		{address: "a@example.com", replyTo: "help@example.com"},
+		{address: "b@example.com", replyTo: "sales@example.com"},
+	})
+	if err == nil {
+		t.Error("Expected an error for the differing reply-to addresses")
+	}
+}
+
+func TestMergeReplyTos(t *testing.T) {
+	replyTo, list, err := mergeReplyTos("help@example.com", []string{"HELP@example.com", "sales@example.com"})
+	if err != nil || replyTo != "" || !reflect.DeepEqual(list, []string{"help@example.com", "sales@example.com"}) {
+		t.Errorf("Expected the deduplicated reply-to list, got %q, %q (%v)", replyTo, list, err)
+	}
+	replyTo, list, err = mergeReplyTos("", []string{"help@example.com"})
+	if err != nil || replyTo != "help@example.com" || list != nil {
+		t.Errorf("Expected a single reply-to address, got %q, %q (%v)", replyTo, list, err)
+	}
+	if _, _, err = mergeReplyTos("", []string{"help"}); err == nil {
+		t.Error("Expected an error for the incorrect address")
+	}
+}
+
+func TestSeparatePersonalizations(t *testing.T) {
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com"},
+		ccs:              []string{"cc@example.com"},
+		separate:         true,
+		subject:          "Test",
+		plainTextContent: "Test",
+	})
+	if len(message.Personalizations) != 2 {
+		t.Fatalf("Expected 2 personalizations, got %d", len(message.Personalizations))
+	}
+	for i, expected := range []string{"a@example.com", "b@example.com"} {
+		if tos := message.Personalizations[i].To; len(tos) != 1 || tos[0].Address != expected {
+			t.Errorf("Expected the personalization %d to be addressed to %q, got %v", i, expected, tos)
+		}
+	}
+}
+
+func TestPersonalizationSubjects(t *testing.T) {
+	subjects, err := recipientSubjects(
+		[]recipient{{address: "Alice <a@Example.com>", subject: "Hi Alice"}},
+		[]string{"b@example.com=Hi Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"Alice <a@Example.com>", "b@example.com"},
+		separate:         true,
+		subject:          "Test",
+		subjects:         subjects,
+		plainTextContent: "Test",
+	})
+	for i, expected := range []string{"Hi Alice", "Hi Bob"} {
+		if s := message.Personalizations[i].Subject; s != expected {
+			t.Errorf("Expected the personalization %d subject %q, got %q", i, expected, s)
+		}
+	}
+	if message.Subject != "Test" {
+		t.Errorf("Expected the message subject to be kept, got %q", message.Subject)
+	}
+	if _, err := recipientSubjects(nil, []string{"Hi Bob"}); err == nil {
+		t.Error("Expected an error for the subject without the address")
+	}
+}
+
+func TestFilterRecipients(t *testing.T) {
+	tos, err := filterRecipients("grep -v '@blocked.com'",
+		[]string{"a@example.com", "b@blocked.com", "Carol <c@example.com>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tos) != 2 || tos[0] != "a@example.com" || tos[1] != "Carol <c@example.com>" {
+		t.Errorf("Unexpected filtered recipients: %v", tos)
+	}
+	if _, err := filterRecipients("exit 1", []string{"a@example.com"}); err == nil {
+		t.Error("Expected an error on the filter failure")
+	}
+}
+
+func TestPersonalizationSendAt(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return time.Date(2017, 10, 1, 10, 0, 0, 0, time.UTC) }
+
+	filename := tempFile(t, "email,send_at\na@example.com,2017-10-01T12:00:00Z\nb@example.com,1506960000\n")
+	defer os.Remove(filename)
+	recipients, err := readRecipients(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendAts, err := recipientSendAts(recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{recipients[0].address, recipients[1].address},
+		separate:         true,
+		subject:          "Test",
+		sendAts:          sendAts,
+		plainTextContent: "Test",
+	})
+	for i, expected := range []int{1506859200, 1506960000} {
+		if sendAt := message.Personalizations[i].SendAt; sendAt != expected {
+			t.Errorf("Expected the personalization %d send_at %d, got %d", i, expected, sendAt)
+		}
+	}
+
+	if _, err := recipientSendAts([]recipient{{address: "a@example.com", sendAt: "2017-10-05T10:00:00Z"}}); err == nil {
+		t.Error("Expected an error for the send time beyond 72 hours")
+	}
+	if _, err := parseSendAt("tomorrow"); err == nil {
+		t.Error("Expected an error for the incorrect send time")
+	}
+}
+
+func TestMaxSendAtWindow(t *testing.T) {
+	defer func(n func() time.Time, w time.Duration) { now, maxSendAtWindow = n, w }(now, maxSendAtWindow)
+	now = func() time.Time { return time.Date(2017, 10, 1, 10, 0, 0, 0, time.UTC) }
+
+	const fiveDaysAhead = "2017-10-06T10:00:00Z"
+	if _, err := parseSendAt(fiveDaysAhead); err == nil {
+		t.Error("Expected an error for the send time beyond the default window")
+	}
+	RootCmd.PersistentFlags().Set("max-send-at-window", "168h")
+	defer RootCmd.PersistentFlags().Set("max-send-at-window", "72h")
+	debugCmd(RootCmd)
+	if sendAt, err := parseSendAt(fiveDaysAhead); err != nil || sendAt != 1507284000 {
+		t.Errorf("Expected the send time within the overridden window to be accepted, got %d (%v)", sendAt, err)
+	}
+	if _, err := parseSendAt("2017-10-09T10:00:00Z"); err == nil {
+		t.Error("Expected an error for the send time beyond the overridden window")
+	}
+}
+
+func TestDedupeAcrossLists(t *testing.T) {
+	filename := tempFile(t, "# CC list\nShared <Shared@Example.com>\n\ncc@example.com\n")
+	defer os.Remove(filename)
+	ccs, err := readAddresses(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tos := []string{"shared@example.com", "to@example.com"}
+	var bccs []string
+	output := captureLog(func() {
+		ccs, bccs = dedupeAcrossLists(tos, ccs, []string{"cc@example.com", "bcc@example.com", "shared@example.com"})
+	})
+	if len(ccs) != 1 || ccs[0] != "cc@example.com" {
+		t.Errorf("Expected the shared address to be removed from CC, got %v", ccs)
+	}
+	if len(bccs) != 1 || bccs[0] != "bcc@example.com" {
+		t.Errorf("Expected the duplicates to be removed from BCC, got %v", bccs)
+	}
+	if !strings.Contains(output, "already in the TO list") || !strings.Contains(output, "already in the CC list") {
+		t.Errorf("Expected the removals to be logged, got %q", output)
+	}
+}
+
+func TestRecipientSubusers(t *testing.T) {
+	var requests []string
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Personalizations []struct {
+				To []map[string]string `json:"to"`
+			} `json:"personalizations"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body.Personalizations[0].To[0]["email"]+" "+r.Header.Get("on-behalf-of"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	filename := tempFile(t, "email,subuser\na@example.com,agency-client-a\nb@example.com,client.b\n")
+	defer os.Remove(filename)
+	recipients, err := readRecipients(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subusers, err := recipientSubusers(recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com"},
+		separate:         true,
+		subject:          "Test",
+		plainTextContent: "Test",
+		subusers:         subusers,
+	}
+	err = sendInBatches(p, &bulkOptions{batchSize: 1}, func(b *sendParams) error { return deliverV3("API-KEY", b) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a@example.com agency-client-a", "b@example.com client.b"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected the requests %q, got %q", expected, requests)
+	}
+
+	if _, err := recipientSubusers([]recipient{{address: "a@example.com", subuser: "bad name"}}); err == nil {
+		t.Error("Expected an error for the incorrect subuser name")
+	}
+}
+
+func TestReplyToRecipientWarnings(t *testing.T) {
+	warnings := replyToRecipientWarnings([]string{"Help <HELP@example.com>", "sales@example.com"},
+		[]string{"to@example.com"}, []string{"help@example.com"}, []string{"to@example.com"})
+	expected := []string{
+		"The reply-to address Help <HELP@example.com> is also a CC recipient, the replies may loop back to it",
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected the warnings %q, got %q", expected, warnings)
+	}
+
+	toFile := tempFile(t, "to@example.com\n")
+	defer os.Remove(toFile)
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = ioutil.Discard
+	defer func() {
+		for name, value := range map[string]string{
+			"key": "", "to-file": "", "subject": "", "plain-text": "", "reply-to": "", "dry-run": "false"} {
+			RootCmd.PersistentFlags().Set(name, value)
+			RootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+	}()
+	RootCmd.SetArgs([]string{"-k", "API-KEY", "--to-file", toFile, "--reply-to", "TO@example.com",
+		"-s", "Test", "--plain-text", "Test", "--dry-run"})
+	output := captureLog(func() {
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(output, "The reply-to address TO@example.com is also a TO recipient") {
+		t.Errorf("Expected the reply-to warning, got:\n%s", output)
+	}
+}
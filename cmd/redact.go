@@ -0,0 +1,60 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"regexp"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Set if the addresses should be masked in the log output
+var redactingRecipients bool
+
+var emailAddress = regexp.MustCompile(`([A-Za-z0-9!#$%&'*+/=?^_{|}~-])[A-Za-z0-9.!#$%&'*+/=?^_{|}~-]*(@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+)`)
+
+// Masks the local parts of the email addresses in the text keeping the first
+// character, eg, j***@example.com.
+func redactAddresses(text string) string {
+	return emailAddress.ReplaceAllString(text, "$1***$2")
+}
+
+// Logging hook masking the addresses in the messages and the string fields
+// of the log entries before they get formatted or passed to the other hooks.
+type redactHook struct{}
+
+func (redactHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (redactHook) Fire(entry *log.Entry) error {
+	if !redactingRecipients {
+		return nil
+	}
+	entry.Message = redactAddresses(entry.Message)
+	data := make(log.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		if s, ok := v.(string); ok {
+			v = redactAddresses(s)
+		}
+		data[k] = v
+	}
+	entry.Data = data
+	return nil
+}
+
+func init() {
+	log.AddHook(redactHook{}) // added first, so that it applies to the syslog output too
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestRedactAddresses(t *testing.T) {
+	for text, expected := range map[string]string{
+		"Removed john.doe@example.com from the CC list": "Removed j***@example.com from the CC list",
+		"John Doe <j+tag@mail.example.co.uk>, a@b.io":   "John Doe <j***@mail.example.co.uk>, a***@b.io",
+		"no addresses @ here":                           "no addresses @ here",
+	} {
+		if redacted := redactAddresses(text); redacted != expected {
+			t.Errorf("Expected %q, got %q", expected, redacted)
+		}
+	}
+}
+
+func TestRedactRecipients(t *testing.T) {
+	var body string
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, q, r bool) { apiHost, quiet, redactingRecipients = h, q, r }(apiHost, quiet, redactingRecipients)
+	apiHost, quiet, redactingRecipients = fakeServer.URL, true, true
+
+	output := captureLog(func() {
+		tos := []string{"john@example.com"}
+		ccs, _ := dedupeAcrossLists(tos, []string{"john@example.com"}, nil)
+		log.WithField("to", tos[0]).Warn("Sending to john@example.com")
+		if err := deliverV3("API-KEY", &sendParams{
+			from:             "sender@example.com",
+			tos:              tos,
+			ccs:              ccs,
+			subject:          "Test",
+			plainTextContent: "Test",
+		}); err != nil {
+			t.Error(err)
+		}
+	})
+	if strings.Contains(output, "john@example.com") || !strings.Contains(output, "j***@example.com") {
+		t.Errorf("Expected the masked addresses in the log output, got:\n%s", output)
+	}
+	if !strings.Contains(body, `"email":"john@example.com"`) {
+		t.Errorf("Expected the real recipient address in the message, got %s", body)
+	}
+}
+
+func TestRedactDebugFlags(t *testing.T) {
+	defer func(w io.Writer, d, r bool, l log.Level) {
+		stdout, debug, redactingRecipients = w, d, r
+		log.SetLevel(l)
+	}(stdout, debug, redactingRecipients, log.GetLevel())
+	stdout = ioutil.Discard
+	var out bytes.Buffer
+	RootCmd.SetOutput(&out)
+	defer RootCmd.SetOutput(nil)
+	defer func() {
+		fromFlag.reset(placeholderFrom)
+		RootCmd.PersistentFlags().Lookup("from").Changed = false
+		for _, name := range []string{"debug", "redact-recipients"} {
+			RootCmd.PersistentFlags().Set(name, "false")
+			RootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+	}()
+	RootCmd.SetArgs([]string{"preview", "--debug", "--redact-recipients", "-f", "john@example.com", "<p>Hi</p>"})
+	output := captureLog(func() {
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	output += out.String()
+	if strings.Contains(output, "john@example.com") || !strings.Contains(output, "--from j***@example.com") {
+		t.Errorf("Expected the masked addresses in the flags debug output, got:\n%s", output)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/russross/blackfriday"
+)
+
+// Renders the Markdown source into the HTML and/or plain-text body depending
+// on the mode (html, plain or both). The plain-text body is converted from
+// the rendered HTML, so both parts come from the same source.
+func renderBody(source, mode string) (htmlContent, plainTextContent string, err error) {
+	switch mode {
+	case "html", "plain", "both":
+	default:
+		return "", "", fmt.Errorf("invalid --render value %q, expected html, plain or both", mode)
+	}
+	htmlContent = string(blackfriday.MarkdownCommon([]byte(source)))
+	if mode != "html" {
+		plainTextContent, err = htmlToText(htmlContent)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if mode == "plain" {
+		htmlContent = ""
+	}
+	return htmlContent, plainTextContent, nil
+}
+
+// Renders the content part as Go text/template with the data. The keys missing
+// in the data are reported as errors.
+func renderTemplate(part, content string, data map[string]interface{}) (string, error) {
+	if content == "" {
+		return "", nil
+	}
+	t, err := template.New(part).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the %s content as template: %v", part, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render the %s content: %v", part, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRenderBody(t *testing.T) {
+	filename := tempFile(t, "# Welcome\n\nHello, **World**!\n\n* one\n* two\n")
+	defer os.Remove(filename)
+	source := readFile(filename, false, false)
+
+	htmlContent, plainTextContent, err := renderBody(source, "both")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(htmlContent, "<h1>Welcome</h1>") || !strings.Contains(htmlContent, "<strong>World</strong>") {
+		t.Errorf("The HTML body should be rendered from the Markdown source: %s", htmlContent)
+	}
+	if !strings.Contains(plainTextContent, "Welcome") || !strings.Contains(plainTextContent, "*World*") ||
+		strings.Contains(plainTextContent, "<") {
+		t.Errorf("The plain-text body should be converted from the same source: %q", plainTextContent)
+	}
+
+	if htmlContent, plainTextContent, _ = renderBody(source, "html"); htmlContent == "" || plainTextContent != "" {
+		t.Errorf("Only the HTML body should be rendered, got %q and %q", htmlContent, plainTextContent)
+	}
+	if htmlContent, plainTextContent, _ = renderBody(source, "plain"); htmlContent != "" || plainTextContent == "" {
+		t.Errorf("Only the plain-text body should be rendered, got %q and %q", htmlContent, plainTextContent)
+	}
+	if _, _, err = renderBody(source, "pdf"); err == nil {
+		t.Error("The invalid render mode should be rejected")
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data, err := parseTemplateData(`{"name": "John", "items": ["tea", "milk"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html, err := renderTemplate("HTML", "<p>Hello, {{.name}}!</p>{{range .items}}<li>{{.}}</li>{{end}}", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html != "<p>Hello, John!</p><li>tea</li><li>milk</li>" {
+		t.Errorf("Unexpected rendered content %q", html)
+	}
+	if _, err := renderTemplate("plain-text", "Hello, {{.surname}}!", data); err == nil {
+		t.Error("Expected an error for the key missing in the data")
+	}
+	if _, err := renderTemplate("plain-text", "Hello, {{.name}!", data); err == nil {
+		t.Error("Expected an error for the incorrect template")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	v2 "sendgrid-cli/sendgrid"
+
+	"github.com/sendgrid/rest"
+)
+
+// Result of the API call sending the message
+type messageResult struct {
+	Recipients []string `json:"recipients"`
+	StatusCode int      `json:"status_code,omitempty"`
+	MessageID  string   `json:"message_id,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Report of the run written with --summary-file
+type runReport struct {
+	Timestamp  time.Time       `json:"timestamp"`
+	Subject    string          `json:"subject"`
+	Recipients []string        `json:"recipients"`
+	Sent       int             `json:"sent"`
+	Failed     int             `json:"failed"`
+	Messages   []messageResult `json:"messages"`
+}
+
+func newRunReport(p *sendParams) *runReport {
+	return &runReport{
+		Timestamp:  now().UTC(),
+		Subject:    p.subject,
+		Recipients: messageRecipients(p),
+		Messages:   []messageResult{},
+	}
+}
+
+// Returns all the recipients of the message.
+func messageRecipients(p *sendParams) []string {
+	recipients := append([]string{}, p.tos...)
+	return append(append(recipients, p.ccs...), p.bccs...)
+}
+
+// Wraps the delivery recording the result of every message. The status
+// code and the message ID are taken from the last API response.
+func (r *runReport) wrap(deliver func(*sendParams) error) func(*sendParams) error {
+	return func(p *sendParams) error {
+		result := messageResult{Recipients: messageRecipients(p)}
+		observe := observeResponse
+		defer func() { observeResponse = observe }()
+		observeResponse = func(response *rest.Response) {
+			observe(response)
+			result.StatusCode = response.StatusCode
+			if ids := response.Headers["X-Message-Id"]; len(ids) > 0 {
+				result.MessageID = ids[0]
+			}
+		}
+		err := deliver(p)
+		if err != nil {
+			result.Error = err.Error()
+			if e, ok := err.(*v2.Error); ok {
+				result.StatusCode = e.StatusCode
+			}
+			r.Failed++
+		} else {
+			r.Sent++
+		}
+		r.Messages = append(r.Messages, result)
+		return err
+	}
+}
+
+// Writes the report as indented JSON.
+func (r *runReport) write(filename string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(b, '\n'), 0644)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRunReport(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return time.Date(2017, 10, 1, 10, 0, 0, 0, time.UTC) }
+	defer func(q bool) { quiet = q }(quiet)
+	quiet = true
+
+	calls := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors":[{"message":"invalid"}]}`))
+			return
+		}
+		w.Header().Set("X-Message-Id", "MESSAGE-"+strconv.Itoa(calls))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com", "c@example.com"},
+		bccs:             []string{"audit@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	report := newRunReport(p)
+	deliver := report.wrap(func(b *sendParams) error { return deliverV3("API-KEY", b) })
+	if err := sendInBatches(p, &bulkOptions{batchSize: 1}, deliver); err == nil {
+		t.Error("Expected the failed batch error")
+	}
+	filename := tempFile(t, "")
+	defer os.Remove(filename)
+	if err := report.write(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written runReport
+	if err := json.Unmarshal(b, &written); err != nil {
+		t.Fatal(err)
+	}
+	expected := runReport{
+		Timestamp:  now(),
+		Subject:    "Test",
+		Recipients: []string{"a@example.com", "b@example.com", "c@example.com", "audit@example.com"},
+		Sent:       2,
+		Failed:     1,
+		Messages: []messageResult{
+			{Recipients: []string{"a@example.com", "audit@example.com"}, StatusCode: 202, MessageID: "MESSAGE-1"},
+			{Recipients: []string{"b@example.com"}, StatusCode: 400,
+				Error: "SendGrid API error (400): " + `{"errors":[{"message":"invalid"}]}`},
+			{Recipients: []string{"c@example.com"}, StatusCode: 202, MessageID: "MESSAGE-3"},
+		},
+	}
+	if !reflect.DeepEqual(written, expected) {
+		t.Errorf("Expected the report:\n%+v\ngot:\n%+v", expected, written)
+	}
+}
+
+func TestRunReportV2(t *testing.T) {
+	defer func(q bool) { quiet = q }(quiet)
+	quiet = true
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":"success"}`))
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	report := newRunReport(p)
+	deliver := report.wrap(func(b *sendParams) error { return deliverV2("user", "password", b) })
+	if err := deliver(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Messages) != 1 || report.Messages[0].StatusCode != http.StatusOK {
+		t.Errorf("Expected the v2 status code in the report, got %+v", report.Messages)
+	}
+}
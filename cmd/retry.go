@@ -0,0 +1,83 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	v2 "sendgrid-cli/sendgrid"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Retry policy of the failed API calls
+type retryPolicy struct {
+	retries   int            // maximum number of the retries (0 - no retries)
+	backoff   time.Duration  // pause before the first retry, doubled on every next one
+	bodyMatch *regexp.Regexp // retry the responses with the matching body regardless of the status code
+}
+
+// Tells whether the failed API call should be retried: the network failures,
+// SendGrid API errors (5xx), throttling (429) and the responses with the body
+// matching the policy's expression are retried. The local errors are not.
+func (r *retryPolicy) retryable(err error) bool {
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		return false
+	}
+	var statusCode int
+	var body string
+	switch e := err.(type) {
+	case *APIError:
+		statusCode, body = e.StatusCode, e.Body
+	case *v2.Error:
+		statusCode, body = e.StatusCode, e.Body
+	case net.Error: // including *url.Error of the failed HTTP requests
+		return true
+	default:
+		return false
+	}
+	if r.bodyMatch != nil && r.bodyMatch.MatchString(body) {
+		return true
+	}
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+// Wraps the delivery retrying the failed API calls according to the policy
+// with the exponential backoff until the context gets cancelled.
+func withRetries(ctx context.Context, r *retryPolicy, deliver func(*sendParams) error) func(*sendParams) error {
+	if r.retries <= 0 {
+		return deliver
+	}
+	return func(p *sendParams) error {
+		backoff := r.backoff
+		for attempt := 1; ; attempt++ {
+			err := deliver(p)
+			if err == nil || attempt > r.retries || !r.retryable(err) {
+				return err
+			}
+			log.Warnf("Attempt %d of %d failed: %v, retrying in %v...", attempt, r.retries+1, err, backoff)
+			sleep(ctx, backoff)
+			if ctx.Err() != nil {
+				log.Warnf("Interrupted before the retry %d of %d.", attempt, r.retries)
+				return errInterrupted
+			}
+			backoff *= 2
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRetryOnBodyMatch(t *testing.T) {
+	var sleeps []time.Duration
+	defer func(s func(context.Context, time.Duration)) { sleep = s }(sleep)
+	sleep = func(ctx context.Context, d time.Duration) { sleeps = append(sleeps, d) }
+
+	calls := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"errors":[{"message":"temporarily unavailable, try again"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	deliver := func(b *sendParams) error { return deliverV3("API-KEY", b) }
+	retry := &retryPolicy{retries: 3, backoff: time.Second, bodyMatch: regexp.MustCompile(`try again`)}
+	if err := withRetries(context.Background(), retry, deliver)(p); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || len(sleeps) != 2 || sleeps[0] != time.Second || sleeps[1] != 2*time.Second {
+		t.Errorf("Expected 2 retries with the exponential backoff, got %d calls and the pauses %v", calls, sleeps)
+	}
+
+	calls = 0
+	retry.bodyMatch = nil
+	if err := withRetries(context.Background(), retry, deliver)(p); err == nil || calls != 1 {
+		t.Errorf("Expected the client error not to be retried without the body match, got %d calls (%v)", calls, err)
+	}
+}
+
+func TestRetryInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func(s func(context.Context, time.Duration)) { sleep = s }(sleep)
+	sleep = func(context.Context, time.Duration) { cancel() } // SIGINT during the backoff
+
+	calls := 0
+	deliver := func(*sendParams) error {
+		calls++
+		return &APIError{StatusCode: http.StatusServiceUnavailable, Body: "unavailable"}
+	}
+	retry := &retryPolicy{retries: 3, backoff: time.Second}
+	err := sendInBatches(&sendParams{tos: []string{"to@example.com"}}, &bulkOptions{ctx: ctx},
+		withRetries(ctx, retry, deliver))
+	if err != errInterrupted || calls != 1 {
+		t.Errorf("Expected the interruption after a single call, got %d calls (%v)", calls, err)
+	}
+	if code := errorExitCode(err); code != exitInterrupted {
+		t.Errorf("Expected the exit code %d, got %d", exitInterrupted, code)
+	}
+}
+
+func TestRetryNetworkErrorsOnly(t *testing.T) {
+	defer func(s func(context.Context, time.Duration)) { sleep = s }(sleep)
+	sleep = func(context.Context, time.Duration) {}
+
+	retry := &retryPolicy{retries: 3, backoff: time.Second}
+	for err, retried := range map[error]bool{
+		&url.Error{Op: "Post", URL: "https://api.sendgrid.com", Err: errors.New("connection refused")}: true,
+		errors.New("failed to read the attachment"):                                                    false,
+		context.DeadlineExceeded: false,
+		errInterrupted:           false,
+	} {
+		calls := 0
+		deliver := func(*sendParams) error {
+			calls++
+			return err
+		}
+		withRetries(context.Background(), retry, deliver)(&sendParams{})
+		if expected := map[bool]int{true: 4, false: 1}[retried]; calls != expected {
+			t.Errorf("Expected %d calls for %v, got %d", expected, err, calls)
+		}
+	}
+}
@@ -15,16 +15,21 @@
 package cmd
 
 import (
-	"bytes"
+	"compress/gzip"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"mime"
-	"mime/multipart"
 	"net/http"
-	"net/url"
+	"os/exec"
+	"path/filepath"
 	"time"
+	"unicode/utf8"
 
 	"os"
 	"regexp"
@@ -36,53 +41,189 @@ import (
 	"github.com/jaytaylor/html2text"
 
 	"github.com/sendgrid/rest"
-	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// Exit codes of the different failure classes
+const (
+	exitUsage       = 2   // incorrect usage or input validation failure
+	exitAPI         = 3   // the API rejected the request (4xx)
+	exitNetwork     = 4   // network failure or the API server error (5xx)
+	exitListed      = 5   // the domain or IP address is on a blocklist
+	exitInterrupted = 130 // interrupted with SIGINT
+)
+
+// Terminates the process with the exit code (can be replaced for testing)
+var exit = os.Exit
+
+// Logs the error and exits with the code.
+func fail(code int, args ...interface{}) {
+	log.Error(args...)
+	exit(code)
+}
+
+// Logs the formatted error and exits with the code.
+func failf(code int, format string, args ...interface{}) {
+	log.Errorf(format, args...)
+	exit(code)
+}
+
+// Maps the response status code to the exit code.
+func statusExitCode(statusCode int) int {
+	if statusCode >= 500 {
+		return exitNetwork
+	}
+	return exitAPI
+}
+
+// Maps the error of the API call to the exit code.
+func errorExitCode(err error) int {
+	switch e := err.(type) {
+	case *APIError:
+		return statusExitCode(e.StatusCode)
+	case *v2.Error:
+		return statusExitCode(e.StatusCode)
+	}
+	if err == errInterrupted {
+		return exitInterrupted
+	}
+	return exitNetwork
+}
+
+// Version of the CLI
+const Version = "0.1.0"
+
+// HTML content used for sending templates without any content
+const dummyContent = "<!-- Dummy Content -->"
+
 var (
-	cfgFile string
-	debug   bool
-	verbose bool
+	cfgFile  string
+	noConfig bool // ignore the config file and the environment
+	debug    bool
+	verbose  bool
+	quiet    bool
+
+	// SendGrid API host (can be overridden for testing)
+	apiHost = "https://api.sendgrid.com"
+	// Standard input used for reading the content piped into the CLI
+	stdin io.Reader = os.Stdin
+	// Standard output used for printing the command results
+	stdout io.Writer = os.Stdout
+	// Minimal TLS version of the connections to the API
+	minTLS uint16 = tls.VersionTLS12
+	// Subuser on behalf of which the API requests are made
+	onBehalfOf string
+	// Timeout of the API requests (0 - the client default)
+	timeout time.Duration
+	// User-Agent header of the API requests
+	userAgent = "sendgrid-cli/" + Version
+	// Render the HTML tables as ASCII table art in the plain-text conversion
+	prettyTables = true
+	// Indent the JSON output
+	prettyJSON bool
+	// The syslog hook is added (once per process)
+	loggingToSyslog bool
 )
 
-// read into a string whole content of a file
-func readFile(filename string) string {
-	b, err := ioutil.ReadFile(filename)
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Certificate authorities the API server certificates are verified with (the
+// system ones if nil)
+var rootCAs *x509.CertPool
+
+// Creates HTTP transport for the API clients enforcing the minimal TLS version.
+func newTransport() *http.Transport {
+	return &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: minTLS}}
+}
+
+// read into a string whole content of a file, decompressing it if the file
+// is gzipped (or its name ends with ".gz") and stripping the leading and
+// trailing whitespace if trim is set
+func readFile(filename string, gzipped, trim bool) string {
+	b, err := readContent(filename, gzipped || strings.HasSuffix(strings.ToLower(filename), ".gz"))
 	if err != nil {
 		log.Errorf("Failed to read the file %q", filename)
-		log.Fatal(err)
+		fail(exitUsage, err)
+	}
+	if trim {
+		return strings.TrimSpace(string(b))
 	}
 	return string(b)
 }
 
+func readContent(filename string, gzipped bool) ([]byte, error) {
+	if !gzipped {
+		return ioutil.ReadFile(filename)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// Lowercases the domain part of the email address. The local part is kept
+// intact since it is case-sensitive as per RFC 5321.
+func normalizeAddress(address string) string {
+	i := strings.LastIndex(address, "@")
+	if i < 0 {
+		return address
+	}
+	return address[:i] + strings.ToLower(address[i:])
+}
+
+// RFC 5322 group address, eg, "Team: a@example.com, b@example.com;"
+var groupAddress = regexp.MustCompile(`^[^<>@"]+:.*;\s*$`)
+
 // Creates email address structure form the given value in differnt formats:
 // "Full Name <name@domain.name>" OR "name@domain.name"
+// The domain part of the address gets lowercased (see normalizeAddress).
+// The group syntax is not supported and gets rejected.
 func createAddress(raw string) *mail.Email {
 	if raw == "" {
-		log.Fatal("Missing email adderess.")
+		fail(exitUsage, "Missing email adderess.")
+	}
+	if groupAddress.MatchString(raw) {
+		failf(exitUsage, "Group addresses are not supported: %s\nPlease use multiple --to flags instead.", raw)
 	}
 	parts := strings.Split(raw, " <")
 	if len(parts) == 0 || parts[0] == "" {
-		log.Fatal("Email address is incorrect:", raw)
+		failf(exitUsage, "Email address is incorrect: %s", raw)
 	}
 	for i, p := range parts {
 		parts[i] = strings.Trim(p, " ><")
 	}
 	if len(parts) < 2 {
-		return mail.NewEmail(parts[0], parts[0])
+		address := normalizeAddress(parts[0])
+		return mail.NewEmail(address, address)
 	}
-	return mail.NewEmail(parts[0], parts[1])
+	return mail.NewEmail(parts[0], normalizeAddress(parts[1]))
+}
+
+// Converts the HTML body into the plain-text body.
+func htmlToText(html string) (string, error) {
+	return html2text.FromString(html, html2text.Options{PrettyTables: prettyTables})
 }
 
 // Search in the arguments for HTML body and plain-text body.
 func messageBodies(args []string) (htmlBody, plainBody string) {
 	if len(args) == 0 {
-		log.Fatal(`Missing message body.
+		fail(exitUsage, `Missing message body.
 
 Need to have at least one specified either with --html and/or --plain options
 or postional parameters.`)
@@ -93,7 +234,7 @@ or postional parameters.`)
 		if matched {
 			htmlBody = b
 			if len(args) == 1 {
-				plainBody, err = html2text.FromString(b, html2text.Options{PrettyTables: true})
+				plainBody, err = htmlToText(b)
 				if err != nil {
 					log.Error("Failed to convert HTML body into plain-text:", err)
 				}
@@ -109,131 +250,994 @@ or postional parameters.`)
 		}
 	}
 	if args[0] == "" {
-		log.Fatal("Missing message body.")
+		fail(exitUsage, "Missing message body.")
 	}
 	return "", args[0]
 }
 
+// Message content sources given on the command line
+type contentSources struct {
+	htmlFilename      string
+	plainTextFilename string
+	plainText         string // inline plain-text content
+	htmlCommand       string // command producing the HTML content
+	plainTextCommand  string // command producing the plain-text content
+	templateID        string
+	gzip              bool // the content files are gzipped
+	trim              bool // strip the leading and trailing whitespace of the content files
+	args              []string
+}
+
+// Classifies the content file by its extension as either HTML or plain-text.
+// The ".gz" suffix of the gzipped files is ignored.
+func classifyContentFile(filename string) (htmlFilename, plainTextFilename string, err error) {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(strings.ToLower(filename), ".gz"))) {
+	case ".html", ".htm":
+		return filename, "", nil
+	case ".txt", ".text":
+		return "", filename, nil
+	}
+	return "", "", fmt.Errorf(
+		"cannot tell the content type of %q by its extension, use --html or --plain instead", filename)
+}
+
+// Resolves HTML and plain-text content of the message from the given sources.
+func resolveContent(c *contentSources) (htmlContent, plainTextContent string) {
+	if c.plainTextFilename != "" && c.plainText != "" {
+		fail(exitUsage, "--plain and --plain-text are mutually exclusive.")
+	}
+	if c.htmlCommand != "" && c.htmlFilename != "" {
+		fail(exitUsage, "--html and --html-cmd are mutually exclusive.")
+	}
+	if c.plainTextCommand != "" && (c.plainTextFilename != "" || c.plainText != "") {
+		fail(exitUsage, "--plain-cmd cannot be combined with --plain or --plain-text.")
+	}
+	if c.htmlFilename != "" || c.plainTextFilename != "" || c.htmlCommand != "" || c.plainTextCommand != "" {
+		if c.htmlFilename != "" {
+			htmlContent = readFile(c.htmlFilename, c.gzip, c.trim)
+		} else if c.htmlCommand != "" {
+			htmlContent = commandContent("--html-cmd", c.htmlCommand, c.trim)
+		}
+		if c.plainTextFilename != "" {
+			plainTextContent = readFile(c.plainTextFilename, c.gzip, c.trim)
+		} else if c.plainTextCommand != "" {
+			plainTextContent = commandContent("--plain-cmd", c.plainTextCommand, c.trim)
+		} else if c.plainText != "" {
+			plainTextContent = c.plainText
+		} else {
+			plainTextContent, _ = htmlToText(htmlContent)
+		}
+	} else if len(c.args) > 0 || (c.templateID == "" && c.plainText == "") {
+		htmlContent, plainTextContent = messageBodies(c.args)
+		if c.plainText != "" {
+			plainTextContent = c.plainText
+		}
+	} else if c.plainText != "" {
+		plainTextContent = c.plainText
+	} else {
+		htmlContent = dummyContent // A work arround to user template
+	}
+	return
+}
+
+// Rejects the content part exceeding the maximum size (0 - unlimited) or
+// containing invalid UTF-8.
+func validateContent(part, content string, maxSize int) error {
+	if maxSize > 0 && len(content) > maxSize {
+		return fmt.Errorf("the %s content is %d bytes, exceeding --max-body-size %d", part, len(content), maxSize)
+	}
+	if !utf8.ValidString(content) {
+		return fmt.Errorf("the %s content is not valid UTF-8", part)
+	}
+	return nil
+}
+
+// Fails unless the message has either a template or a non-empty content part.
+func requireContent(templateID, htmlContent, plainTextContent string) error {
+	if templateID != "" {
+		return nil
+	}
+	if htmlContent == dummyContent {
+		htmlContent = ""
+	}
+	if strings.TrimSpace(htmlContent) == "" && strings.TrimSpace(plainTextContent) == "" {
+		return errors.New("the message has neither a template nor any content, " +
+			"use --template-id, --html, --plain, --plain-text or give the body as an argument")
+	}
+	return nil
+}
+
 // Command execution
 func send(cmd *cobra.Command, args []string) {
 	debugCmd(cmd)
 
+	p, apiKey, username, password := prepareSend(cmd, args)
+	deliverSend(cmd, p, apiKey, username, password)
+}
+
+// Builds the message from the command line and runs the pre-send checks.
+// Returns the message and the credentials it gets sent with.
+func prepareSend(cmd *cobra.Command, args []string) (p *sendParams, apiKey, username, password string) {
 	if len(args) > 2 {
-		log.Fatalf("Too many positional argumets: %v", args)
+		failf(exitUsage, "Too many positional argumets: %v", args)
+	}
+	var spec *messageSpec
+	if specFilename := flagString(cmd, "from-file"); specFilename != "" {
+		var err error
+		if spec, err = readMessageSpec(specFilename); err != nil {
+			log.Errorf("Failed to read the message spec %q", specFilename)
+			fail(exitUsage, err)
+		}
+		if err := applyMessageSpec(cmd, spec); err != nil {
+			fail(exitUsage, err)
+		}
 	}
 
 	from := flagString(cmd, "from")
+	if !cmd.Flags().Changed("from") {
+		from = defaultFrom()
+	}
+	var rawFrom *mail.Email
+	if flagBool(cmd, "from-raw") {
+		if rawFrom = mail.NewEmail(flagString(cmd, "from-name"), flagString(cmd, "from-addr")); rawFrom.Address == "" {
+			fail(exitUsage, "--from-raw requires the address given with --from-addr.")
+		}
+		if rawFrom.Name == "" {
+			rawFrom.Name = flagString(cmd, "from-name-fallback")
+		}
+		from = rawFrom.Address
+	} else if err := checkFrom(from); err != nil {
+		fail(exitUsage, err)
+	} else {
+		from = withFallbackName(from, flagString(cmd, "from-name-fallback"))
+	}
+	var froms []string
+	if cmd.Flags().Changed("from") && len(fromFlag.addresses) > 1 {
+		if rawFrom != nil {
+			fail(exitUsage, "Multiple --from addresses cannot be combined with --from-raw.")
+		}
+		for _, f := range fromFlag.addresses {
+			if err := checkFrom(f); err != nil {
+				fail(exitUsage, err)
+			}
+			froms = append(froms, withFallbackName(f, flagString(cmd, "from-name-fallback")))
+		}
+	}
 	subject := flagString(cmd, "subject")
-	if subject == "" {
-		log.Fatal(`The subject is required. You can get around this requirement if you use 
+	tos := flagStringArray(cmd, "to")
+	if flagBool(cmd, "interactive") {
+		if isTerminal() {
+			d := &draft{tos: tos, subject: subject}
+			if len(args) > 0 {
+				d.body = args[0]
+			}
+			if err := compose(stdin, stdout, d); err != nil {
+				fail(exitUsage, err)
+			}
+			tos, subject, args = d.tos, d.subject, []string{d.body}
+		} else {
+			log.Warn("The standard input is not a terminal, ignoring --interactive.")
+		}
+	}
+	if subject == "" && flagString(cmd, "template-id") == "" && flagString(cmd, "template-name") == "" {
+		fail(exitUsage, `The subject is required. You can get around this requirement if you use 
 a template with a subject defined or if every personalization has a subject defined.`)
 	}
-	tos := flagStringArray(cmd, "to")
+	tos = append(tos, flagAddresses(cmd, "to-file")...)
+	var recipients []recipient
+	if recipientsFilename := flagString(cmd, "recipients"); recipientsFilename != "" {
+		columnMap, err := parseColumnMap(flagString(cmd, "map"))
+		if err != nil {
+			fail(exitUsage, err)
+		}
+		if recipients, err = readRecipients(recipientsFilename, columnMap); err != nil {
+			log.Errorf("Failed to read the recipients from %q", recipientsFilename)
+			fail(exitUsage, err)
+		}
+		for _, r := range recipients {
+			tos = append(tos, r.address)
+		}
+	}
+	if filter := flagString(cmd, "recipient-filter"); filter != "" {
+		var err error
+		if tos, err = filterRecipients(filter, tos); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+	subjectPrefix := flagString(cmd, "subject-prefix")
+	subject = prefixSubject(subjectPrefix, subject)
+	subjects, err := recipientSubjects(recipients, flagStringArray(cmd, "personalization-subject"))
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	sendAts, err := recipientSendAts(recipients)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	subusers, err := recipientSubusers(recipients)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	if (len(subjects) > 0 || len(sendAts) > 0 || len(subusers) > 0) && !flagBool(cmd, "separate") {
+		fail(exitUsage, "The per-recipient subjects, send times and subusers require --separate.")
+	}
+	var sendAt int
+	if raw := flagString(cmd, "send-at"); raw != "" {
+		if sendAt, err = parseSendAt(raw); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+	maxSubjectLength, strict := flagInt(cmd, "max-subject-length"), flagBool(cmd, "strict")
+	if err := checkSubjectLength(subject, maxSubjectLength, strict); err != nil {
+		fail(exitUsage, err)
+	}
+	for address, s := range subjects {
+		subjects[address] = prefixSubject(subjectPrefix, s)
+		if err := checkSubjectLength(subjects[address], maxSubjectLength, strict); err != nil {
+			fail(exitUsage, err)
+		}
+	}
 	if len(tos) == 0 {
-		log.Fatal(
+		fail(exitUsage,
 			"At lease one recepient should be present. Please -t or --to flag to specify a recepient.")
 	}
 
-	ccs := flagStringArray(cmd, "cc")
+	ccs := append(flagStringArray(cmd, "cc"), flagAddresses(cmd, "cc-file")...)
+	bccs := append(flagStringArray(cmd, "bcc"), flagAddresses(cmd, "bcc-file")...)
+	ccs, bccs, err = copySelf(from, ccs, bccs, flagBool(cmd, "cc-self"), flagBool(cmd, "bcc-self"))
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	if flagBool(cmd, "dedupe-across-lists") {
+		ccs, bccs = dedupeAcrossLists(tos, ccs, bccs)
+	}
+	if err := checkMaxRecipients(flagInt(cmd, "max-recipients"), tos, ccs, bccs); err != nil {
+		fail(exitUsage, err)
+	}
+	if flagBool(cmd, "check-mx") {
+		var addresses []string
+		for _, raw := range append(append(append([]string{}, tos...), ccs...), bccs...) {
+			addresses = append(addresses, createAddress(raw).Address)
+		}
+		if err := checkMX(addresses, flagBool(cmd, "strict")); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+
+	replyTo, err := resolveReplyTo(from, flagString(cmd, "reply-to"), flagBool(cmd, "reply-to-from"))
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	if replyTo, err = recipientsReplyTo(replyTo, recipients); err != nil {
+		fail(exitUsage, err)
+	}
+	var replyToList []string
+	if replyToFilename := flagString(cmd, "reply-to-file"); replyToFilename != "" {
+		if replyTo, replyToList, err = mergeReplyTos(replyTo, flagAddresses(cmd, "reply-to-file")); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+	replyTos := replyToList
+	if replyTo != "" {
+		replyTos = []string{replyTo}
+	}
+	for _, w := range replyToRecipientWarnings(replyTos, tos, ccs, bccs) {
+		log.Warn(w)
+	}
+
+	apiKey = flagString(cmd, "key")
+	username = flagString(cmd, "user")
+	password = flagString(cmd, "password")
 
-	var htmlContent, plainTextContent, templateID string
-	htmlFilename, plainTextFilename := flagString(cmd, "html"), flagString(cmd, "plain")
-	templateID = flagString(cmd, "template-id")
-	if htmlFilename != "" || plainTextFilename != "" {
-		if htmlFilename != "" {
-			htmlContent = readFile(htmlFilename)
+	version := flagString(cmd, "api-version")
+	if version == "" {
+		if err := checkCredentials(apiKey, username, password, flagBool(cmd, "strict")); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+	if apiKey, err = selectAPIVersion(version, apiKey, username, password); err != nil {
+		fail(exitUsage, err)
+	}
+	if apiKey == "" && username == "" {
+		apiKey = getenv("SENDGRID_API_KEY")
+		if apiKey == "" {
+			log.Info("Missing username. Please use --user and --password options.")
+			log.Info("Missing Sendgrid API key. Use --key option.")
+			fail(exitUsage, "Ether Sandgrid API Key or username and password should be present.")
+		}
+	}
+	if len(replyToList) > 0 && apiKey == "" {
+		fail(exitUsage, "Multiple REPLY-TO addresses require SendGrid API key.")
+	}
+	if len(subusers) > 0 && apiKey == "" {
+		fail(exitUsage, "Sending on behalf of the subusers requires SendGrid API key.")
+	}
+	if abort, skip := flagBool(cmd, "abort-on-suppressed"), flagBool(cmd, "skip-suppressed"); abort || skip {
+		if abort && skip {
+			fail(exitUsage, "--abort-on-suppressed and --skip-suppressed are mutually exclusive.")
+		}
+		if apiKey == "" {
+			log.Warn("The suppression check requires SendGrid API key, skipping it.")
+		} else if !(flagBool(cmd, "sandbox") && flagBool(cmd, "dry-run")) {
+			rest.DefaultClient.HTTPClient.Transport = newTransport()
+			suppressed, err := fetchSuppressed(apiKey)
+			if err != nil {
+				log.Error("Failed to retrieve the suppression lists.")
+				fail(errorExitCode(err), err)
+			}
+			if tos, ccs, bccs, err = checkSuppressed(suppressed, skip, tos, ccs, bccs); err != nil {
+				fail(exitUsage, err)
+			}
 		}
-		if plainTextFilename != "" {
-			plainTextContent = readFile(plainTextFilename)
+	}
+
+	templateID := flagString(cmd, "template-id")
+	if templateName := flagString(cmd, "template-name"); templateName != "" {
+		if templateID != "" {
+			fail(exitUsage, "--template-id and --template-name are mutually exclusive.")
+		}
+		if apiKey == "" {
+			fail(exitUsage, "The template lookup by name requires SendGrid API key.")
+		}
+		// The dry run in the sandbox mode makes no API calls at all:
+		if flagBool(cmd, "sandbox") && flagBool(cmd, "dry-run") {
+			log.Warnf("The template name %q is not looked up in the sandbox dry run, using it as the template ID.", templateName)
+			templateID = templateName
 		} else {
-			plainTextContent, _ = html2text.FromString(htmlContent, html2text.Options{PrettyTables: true})
+			rest.DefaultClient.HTTPClient.Transport = newTransport()
+			if templateID, err = templateIDByName(apiKey, templateName); err != nil {
+				log.Error("Failed to look up the template.")
+				fail(errorExitCode(err), err)
+			}
+		}
+	}
+
+	htmlFilename, plainTextFilename := flagString(cmd, "html"), flagString(cmd, "plain")
+	if contentFilename := flagString(cmd, "content"); contentFilename != "" {
+		if htmlFilename != "" || plainTextFilename != "" {
+			fail(exitUsage, "--content cannot be combined with --html or --plain.")
+		}
+		if htmlFilename, plainTextFilename, err = classifyContentFile(contentFilename); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+	var htmlContent, plainTextContent string
+	if bodyFilename := flagString(cmd, "body"); bodyFilename != "" {
+		if htmlFilename != "" || plainTextFilename != "" || flagString(cmd, "content") != "" ||
+			flagString(cmd, "html-cmd") != "" || flagString(cmd, "plain-cmd") != "" {
+			fail(exitUsage, "--body cannot be combined with --html, --plain, --html-cmd, --plain-cmd or --content.")
+		}
+		source := readFile(bodyFilename, flagBool(cmd, "gzip"), flagBool(cmd, "trim"))
+		htmlContent, plainTextContent, err = renderBody(source, flagString(cmd, "render"))
+		if err != nil {
+			fail(exitUsage, err)
 		}
-	} else if templateID == "" || len(args) > 0 {
-		htmlContent, plainTextContent = messageBodies(args)
 	} else {
-		if templateID == "" {
-			log.Fatal("Need to have at least one way of providing the message content.")
+		htmlContent, plainTextContent = resolveContent(&contentSources{
+			htmlFilename:      htmlFilename,
+			plainTextFilename: plainTextFilename,
+			gzip:              flagBool(cmd, "gzip"),
+			trim:              flagBool(cmd, "trim"),
+			plainText:         flagString(cmd, "plain-text"),
+			htmlCommand:       flagString(cmd, "html-cmd"),
+			plainTextCommand:  flagString(cmd, "plain-cmd"),
+			templateID:        templateID,
+			args:              args,
+		})
+	}
+	maxBodySize := flagInt(cmd, "max-body-size")
+	if err := validateContent("HTML", htmlContent, maxBodySize); err != nil {
+		fail(exitUsage, err)
+	}
+	if err := validateContent("plain-text", plainTextContent, maxBodySize); err != nil {
+		fail(exitUsage, err)
+	}
+	if err := requireContent(templateID, htmlContent, plainTextContent); err != nil {
+		fail(exitUsage, err)
+	}
+	if flagBool(cmd, "inline-css") && htmlContent != dummyContent && htmlContent != "" {
+		if flagBool(cmd, "sanitize") {
+			fail(exitUsage, "--inline-css cannot be combined with --sanitize, which strips the styles.")
 		}
-		htmlContent = "<!-- Dummy Content -->" // A work arround to user template
+		htmlContent = inlineCSS(htmlContent)
+	}
+	if flagBool(cmd, "sanitize") && htmlContent != dummyContent && htmlContent != "" {
+		htmlContent = sanitizeHTML(htmlContent)
 	}
 	if debug {
 		log.Infof("HTML Content:\n=============\n%s", htmlContent)
 		log.Infof("Plain Text Content:\n===================\n%s", plainTextContent)
 	}
 
-	apiKey := flagString(cmd, "key")
-	username := flagString(cmd, "user")
-	password := flagString(cmd, "password")
+	subs := flagStringArray(cmd, "sub")
+	if subFilename := flagString(cmd, "sub-file"); subFilename != "" {
+		fileSubs, err := readSubstitutions(subFilename)
+		if err != nil {
+			log.Errorf("Failed to read the substitutions from %q", subFilename)
+			fail(exitUsage, err)
+		}
+		subs = append(fileSubs, subs...)
+	}
 
-	if apiKey == "" && username == "" {
-		apiKey = os.Getenv("SENDGRID_API_KEY")
+	rawHeaders := flagStringArray(cmd, "header")
+	if headerFilename := flagString(cmd, "header-file"); headerFilename != "" {
+		fileHeaders, err := readHeaders(headerFilename)
+		if err != nil {
+			log.Errorf("Failed to read the headers from %q", headerFilename)
+			fail(exitUsage, err)
+		}
+		rawHeaders = append(fileHeaders, rawHeaders...)
+	}
+	if raw := flagString(cmd, "header-json"); raw != "" {
+		jsonHeaders, err := parseHeadersJSON(raw)
+		if err != nil {
+			log.Error("Failed to parse the headers JSON.")
+			fail(exitUsage, err)
+		}
+		rawHeaders = append(jsonHeaders, rawHeaders...)
+	}
+	if flagBool(cmd, "require-tls") {
+		rawHeaders = append(rawHeaders, requireTLSHeader+": true")
+	}
+	if raw := flagString(cmd, "message-id"); raw != "" {
+		id, err := formatMessageID(raw)
+		if err != nil {
+			fail(exitUsage, err)
+		}
+		rawHeaders = append(rawHeaders, messageIDHeader+": "+id)
+	}
+	headers, err := collectHeaders(rawHeaders)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+
+	p = &sendParams{
+		from:             from,
+		froms:            froms,
+		rawFrom:          rawFrom,
+		tos:              tos,
+		ccs:              ccs,
+		bccs:             bccs,
+		replyTo:          replyTo,
+		replyToList:      replyToList,
+		separate:         flagBool(cmd, "separate"),
+		subject:          subject,
+		subjects:         subjects,
+		sendAt:           sendAt,
+		sendAts:          sendAts,
+		subusers:         subusers,
+		htmlContent:      htmlContent,
+		plainTextContent: plainTextContent,
+		templateID:       templateID,
+		subs:             subs,
+		headers:          headers,
+		noTracking:       flagBool(cmd, "no-tracking"),
+		sandbox:          flagBool(cmd, "sandbox"),
+		dumpCurl:         flagBool(cmd, "dump-curl"),
+		attFilenames:     flagStringArray(cmd, "att"),
+		attOrder:         append([]string{}, attachmentFlagOrder...),
+		attCharset:       flagString(cmd, "attachment-charset"),
+	}
+	if spec != nil {
+		p.trackingSettings = spec.Tracking.settings()
+	}
+	if raw := flagString(cmd, "data"); raw != "" {
+		data, err := parseTemplateData(raw)
+		if err != nil {
+			log.Error("Failed to parse the dynamic template data.")
+			fail(exitUsage, err)
+		}
+		p.templateData = data
+	}
+	if flagBool(cmd, "render-local") {
+		if templateID != "" {
+			fail(exitUsage, "--render-local cannot be combined with --template-id or --template-name.")
+		}
+		if htmlContent, err = renderTemplate("HTML", htmlContent, p.templateData); err != nil {
+			fail(exitUsage, err)
+		}
+		if p.plainTextContent, err = renderTemplate("plain-text", plainTextContent, p.templateData); err != nil {
+			fail(exitUsage, err)
+		}
+		p.htmlContent, p.templateData = htmlContent, nil
+	}
+	if encoding := flagString(cmd, "plain-encoding"); encoding != plainEncodingNone {
+		if p.plainTextContent, err = preparePlainText(p.plainTextContent, encoding); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+	// The attachments given with --att-inline come first, so that they can be
+	// interleaved with --att in the command-line order:
+	for _, spec := range flagStringArray(cmd, "att-inline") {
+		a, err := parseInlineAttachment(spec)
+		if err != nil {
+			fail(exitUsage, err)
+		}
+		p.inlineAtts = append(p.inlineAtts, a)
+	}
+	if flagBool(cmd, "auto-inline") && htmlContent != dummyContent && htmlContent != "" {
+		baseDir := "."
+		if htmlFilename != "" {
+			baseDir = filepath.Dir(htmlFilename)
+		}
+		threshold := int64(flagInt(cmd, "auto-inline-threshold"))
+		var images []*inlineAttachment
+		if p.htmlContent, images, err = autoInlineImages(htmlContent, baseDir, threshold, flagString(cmd, "cid-strategy")); err != nil {
+			fail(exitUsage, err)
+		}
+		p.inlineAtts = append(p.inlineAtts, images...)
+	}
+	for _, filename := range flagStringArray(cmd, "vcard") {
+		a, err := vcardAttachment(filename)
+		if err != nil {
+			log.Errorf("Failed to read the vCard %q", filename)
+			fail(exitUsage, err)
+		}
+		p.inlineAtts = append(p.inlineAtts, a)
+	}
+
+	// The dry run in the sandbox mode makes no API calls at all:
+	if templateID != "" && flagBool(cmd, "verify-template") && !(p.sandbox && flagBool(cmd, "dry-run")) {
 		if apiKey == "" {
-			log.Info("Missing username. Please use --user and --password options.")
-			log.Info("Missing Sendgrid API key. Use --key option.")
-			log.Fatal("Ether Sandgrid API Key or username and password should be present.")
+			log.Warn("The template verification requires SendGrid API key, skipping it.")
+		} else {
+			rest.DefaultClient.HTTPClient.Transport = newTransport()
+			t, err := fetchTemplate(apiKey, templateID)
+			if err != nil {
+				log.Error("Failed to verify the template.")
+				fail(errorExitCode(err), err)
+			}
+			for _, w := range templateGenerationWarnings(t, p.subs, p.templateData) {
+				log.Warn(w)
+			}
 		}
 	}
 
-	subs := flagStringArray(cmd, "sub")
-	attFilenames := flagStringArray(cmd, "att")
+	if flagBool(cmd, "check-sender") && !(p.sandbox && flagBool(cmd, "dry-run")) {
+		if apiKey == "" {
+			log.Warn("The sender verification check requires SendGrid API key, skipping it.")
+		} else {
+			rest.DefaultClient.HTTPClient.Transport = newTransport()
+			warnings, err := senderWarnings(apiKey, senderAddress(p).Address)
+			if err != nil {
+				log.Error("Failed to check the sender.")
+				fail(errorExitCode(err), err)
+			}
+			for _, w := range warnings {
+				log.Warn(w)
+			}
+		}
+	}
+	if flagBool(cmd, "check-dns") {
+		for _, w := range dkimWarnings(addressDomain(senderAddress(p).Address)) {
+			log.Warn(w)
+		}
+	}
 
+	return p, apiKey, username, password
+}
+
+// Sends the prepared message (or prints it in the dry run) honouring the
+// batching, throttling, retry and repeat options.
+func deliverSend(cmd *cobra.Command, p *sendParams, apiKey, username, password string) {
+	if flagBool(cmd, "dry-run") {
+		dryRun(apiKey, p)
+		return
+	}
+
+	var err error
+	deliver := func(b *sendParams) error { return deliverV3(apiKey, b) }
 	if apiKey == "" {
-		sendV2(username, password, from, tos, ccs, subject, htmlContent, plainTextContent, attFilenames)
+		deliver = func(b *sendParams) error { return deliverV2(username, password, b) }
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	retry := &retryPolicy{retries: flagInt(cmd, "retries"), backoff: flagDuration(cmd, "retry-backoff")}
+	if pattern := flagString(cmd, "retry-on-body-match"); pattern != "" {
+		if retry.retries <= 0 {
+			fail(exitUsage, "--retry-on-body-match requires --retries.")
+		}
+		if retry.bodyMatch, err = regexp.Compile(pattern); err != nil {
+			failf(exitUsage, "Incorrect --retry-on-body-match expression: %v", err)
+		}
+	}
+	if flagBool(cmd, "rate-limit-aware") {
+		limiter := newRateLimiter()
+		defer func(o func(*rest.Response)) { observeResponse = o }(observeResponse)
+		observeResponse = func(response *rest.Response) { limiter.observe(response.Headers) }
+		deliver = limiter.wrap(ctx, deliver)
+	}
+	deliver = withRetries(ctx, retry, deliver)
+	if len(p.froms) > 1 {
+		if deliver, err = withFromRotation(p.froms, flagString(cmd, "from-rotation"), deliver); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+	var report *runReport
+	summaryFilename := flagString(cmd, "summary-file")
+	if summaryFilename != "" {
+		report = newRunReport(p)
+		deliver = report.wrap(deliver)
+	}
+	opts := &bulkOptions{ctx: ctx, batchSize: flagInt(cmd, "batch-size"), failFast: flagBool(cmd, "fail-fast")}
+	if opts.failFast && cmd.Flags().Changed("continue-on-error") && flagBool(cmd, "continue-on-error") {
+		fail(exitUsage, "--fail-fast and --continue-on-error are mutually exclusive.")
+	}
+	if opts.interval, err = sendInterval(flagDuration(cmd, "delay"), flagInt(cmd, "rate")); err != nil {
+		fail(exitUsage, err)
+	}
+	if p.separate && opts.interval > 0 {
+		opts.batchSize = 1 // an individual message per recipient sent at the throttled pace
+	}
+	if len(p.subusers) > 0 {
+		opts.batchSize = 1 // the subuser is set per API call
+	}
+	opts.batchSize = personalizationBatchSize(p, opts.batchSize)
+	sendOnce := func() error { return sendInBatches(p, opts, deliver) }
+	if repeat := flagInt(cmd, "repeat"); repeat > 1 {
+		if repeat > repeatConfirmThreshold &&
+			!confirm(fmt.Sprintf("Are you sure you want to send the message %d times?", repeat)) {
+			fail(exitUsage, "Aborted.")
+		}
+		err = repeatSend(repeat, opts, sendOnce)
 	} else {
-		sendV3(apiKey, from, tos, ccs, subject, htmlContent, plainTextContent, templateID, subs, attFilenames)
+		err = sendOnce()
+	}
+	if report != nil {
+		if e := report.write(summaryFilename); e != nil {
+			log.Errorf("Failed to write the run report %q: %v", summaryFilename, e)
+		}
+	}
+	if err != nil {
+		log.Error("Failed to send the message.")
+		fail(errorExitCode(err), err)
 	}
 }
 
-func sendV2(username, password, from string, tos, ccs []string,
-	subject, htmlContent, plainTextContent string, attFilenames []string) {
-	sg := v2.NewSendGridClient(username, password)
-	sg.Client = &http.Client{
-		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
-		Timeout:   5 * time.Second,
+// Selects the API the message gets sent with: v3 with the API key or v2 with
+// the username and password. Returns the API key, empty for v2. Without the
+// version the API key takes precedence.
+func selectAPIVersion(version, apiKey, username, password string) (string, error) {
+	switch version {
+	case "":
+		return apiKey, nil
+	case "2":
+		if username == "" || password == "" {
+			return "", errors.New("--api-version 2 requires the username and password given with --user and --password")
+		}
+		return "", nil
+	case "3":
+		if apiKey == "" {
+			apiKey = getenv("SENDGRID_API_KEY")
+		}
+		if apiKey == "" {
+			return "", errors.New("--api-version 3 requires SendGrid API key given with --key or SENDGRID_API_KEY")
+		}
+		return apiKey, nil
+	}
+	return "", fmt.Errorf("unsupported API version %q, use 2 or 3", version)
+}
+
+// Placeholder FROM address used if no other sender is found
+const placeholderFrom = "sendgrid-cli@nowitworks.eu"
+
+// Looks up the user email in the git configuration (can be replaced for testing).
+var gitUserEmail = func() string {
+	out, err := exec.Command("git", "config", "user.email").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Resolves FROM address if it isn't given with --from: the environment
+// variable SENDGRID_FROM, then the git user email, then the placeholder
+// (right away with --no-config).
+func defaultFrom() string {
+	if noConfig {
+		return placeholderFrom
+	}
+	if from := getenv("SENDGRID_FROM"); from != "" {
+		log.Info("Using FROM address from SENDGRID_FROM: ", from)
+		return from
+	}
+	if from := gitUserEmail(); from != "" {
+		log.Info("Using FROM address from git config user.email: ", from)
+		return from
+	}
+	return placeholderFrom
+}
+
+// Prepends the prefix to the subject unless it is already there, so that
+// a rerun with the already prefixed subject doesn't double-prefix it.
+func prefixSubject(prefix, subject string) string {
+	if prefix == "" || strings.HasPrefix(subject, prefix) {
+		return subject
+	}
+	return prefix + subject
+}
+
+// Warns (or fails in the strict mode) if both the API key and the username
+// or password are given. The API key takes precedence in this case.
+func checkCredentials(apiKey, username, password string, strict bool) error {
+	if apiKey == "" || (username == "" && password == "") {
+		return nil
+	}
+	if strict {
+		return errors.New("both the API key and the username/password are given, use only one of them")
+	}
+	log.Warn("Both the API key and the username/password are given, using the API key.")
+	return nil
+}
+
+// Subject length above which some clients truncate the subject line
+const recommendedSubjectLength = 78
+
+// Warns (or fails in the strict mode) if the subject is longer than the
+// maximum length (0 - unlimited) in characters.
+func checkSubjectLength(subject string, max int, strict bool) error {
+	length := utf8.RuneCountInString(subject)
+	if max > 0 && length > max {
+		if strict {
+			return fmt.Errorf("the subject is %d characters long, exceeding the limit of %d", length, max)
+		}
+		log.Warnf("The subject is %d characters long, exceeding the limit of %d.", length, max)
+	} else if length > recommendedSubjectLength {
+		log.Infof("The subject is %d characters long and may get truncated by the email clients.", length)
+	}
+	return nil
+}
+
+// Adds the sender's address to the CC or BCC list.
+func copySelf(from string, ccs, bccs []string, ccSelf, bccSelf bool) ([]string, []string, error) {
+	if ccSelf && bccSelf {
+		return nil, nil, errors.New("--cc-self and --bcc-self are mutually exclusive")
+	}
+	if ccSelf {
+		ccs = append(ccs, from)
+	}
+	if bccSelf {
+		bccs = append(bccs, from)
+	}
+	return ccs, bccs, nil
+}
+
+// Fails if the total number of the recipients exceeds the limit (0 - unlimited).
+func checkMaxRecipients(max int, lists ...[]string) error {
+	if max <= 0 {
+		return nil
+	}
+	count := 0
+	for _, l := range lists {
+		count += len(l)
+	}
+	if count > max {
+		return fmt.Errorf("the message has %d recipients (To+CC+BCC), exceeding --max-recipients %d", count, max)
+	}
+	return nil
+}
+
+// Adds the fallback display name to the bare address. The address given
+// with a name is kept as is.
+func withFallbackName(address, name string) string {
+	if name == "" || strings.Contains(address, "<") {
+		return address
 	}
+	return name + " <" + strings.TrimSpace(address) + ">"
+}
+
+// Rejects the FROM address given without the email address, eg, only the name.
+func checkFrom(from string) error {
+	if strings.Contains(createAddress(from).Address, "@") {
+		return nil
+	}
+	return fmt.Errorf("the FROM address %q is not an email address, use the \"Name <address>\" form, eg, %q",
+		from, strings.Trim(from, " <>")+" <sender@example.com>")
+}
+
+// Resolves the reply-to address given either explicitly or with --reply-to-from.
+func resolveReplyTo(from, replyTo string, replyToFrom bool) (string, error) {
+	if !replyToFrom {
+		return replyTo, nil
+	}
+	if replyTo != "" {
+		return "", errors.New("--reply-to and --reply-to-from are mutually exclusive")
+	}
+	return from, nil
+}
+
+// Message parameters collected from the command line
+type sendParams struct {
+	from             string
+	rawFrom          *mail.Email // the sender used verbatim, bypassing the address parsing
+	froms            []string    // FROM addresses rotated between the messages
+	tos, ccs, bccs   []string
+	replyTo          string
+	replyToList      []string // multiple reply-to addresses used instead of replyTo
+	separate         bool     // a personalization per TO recipient
+	subject          string
+	subjects         map[string]string // per-recipient subjects in the separate mode
+	sendAt           int               // scheduled delivery UNIX time
+	batchID          string            // batch of the scheduled send, so that it can be paused or cancelled
+	sendAts          map[string]int    // per-recipient delivery times in the separate mode
+	subusers         map[string]string // per-recipient subusers in the separate mode
+	htmlContent      string
+	plainTextContent string
+	templateID       string
+	subs             []string
+	templateData     map[string]interface{} // dynamic template data
+	headers          map[string]string      // custom headers
+	noTracking       bool                   // disable open and click tracking
+	trackingSettings *mail.TrackingSettings // tracking settings of the message spec
+	sandbox          bool                   // validate the message without delivering it
+	attFilenames     []string
+	inlineAtts       []*inlineAttachment
+	attOrder         []string  // attachment flag names in the command-line order
+	dumpCurl         bool      // print the curl command making the API request
+	quiet            bool      // don't print the summary of the sent message
+	dryRunOut        io.Writer // where the dry run prints the message (stdout if nil)
+	attCharset       string    // charset declared in the content type of the text attachments
+}
+
+// Attachment built from the content given on the command line
+type inlineAttachment struct {
+	name        string
+	contentType string
+	content     []byte
+	contentID   string // CID of the attachment displayed inline in the HTML body
+}
+
+// Parses the inline attachment given as "name:type:content". If the content
+// is "@-", it gets read from the standard input. The type can be left empty.
+func parseInlineAttachment(spec string) (*inlineAttachment, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return nil, fmt.Errorf("incorrect inline attachment %q, expected name:type:content", spec)
+	}
+	a := &inlineAttachment{name: parts[0], contentType: parts[1], content: []byte(parts[2])}
+	if a.contentType == "" {
+		a.contentType = attachmentType(a.name)
+	}
+	if parts[2] == "@-" {
+		content, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		a.content = content
+	}
+	return a, nil
+}
+
+// Reads the vCard attachment. It gets the text/vcard type and the .vcf
+// extension regardless of the file name.
+func vcardAttachment(filename string) (*inlineAttachment, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(filename)
+	if ext := filepath.Ext(name); !strings.EqualFold(ext, ".vcf") {
+		name = strings.TrimSuffix(name, ext) + ".vcf"
+	}
+	return &inlineAttachment{name: name, contentType: "text/vcard", content: content}, nil
+}
+
+// Creates SendGrid v2 message
+func newV2Mail(p *sendParams) *v2.SGMail {
 	m := v2.NewMail()
-	m.AddTos(tos)
-	m.AddCcs(ccs)
-	m.SetSubject(subject)
-	if plainTextContent != "" {
-		m.SetText(plainTextContent)
+	m.AddTos(p.tos)
+	m.AddCcs(p.ccs)
+	m.AddBccs(p.bccs)
+	m.SetSubject(p.subject)
+	if p.plainTextContent != "" {
+		m.SetText(p.plainTextContent)
 	}
-	if htmlContent != "" {
-		m.SetHTML(htmlContent)
+	if p.htmlContent != "" {
+		m.SetHTML(p.htmlContent)
 	}
-	m.SetFrom(from)
-	for _, af := range attFilenames {
+	if p.rawFrom != nil {
+		m.From, m.FromName = p.rawFrom.Address, p.rawFrom.Name
+	} else {
+		m.SetFrom(p.from)
+	}
+	// v2 API sends the name raw, so non-ASCII names need RFC 2047 encoding:
+	m.SetFromName(mime.QEncoding.Encode("utf-8", m.FromName))
+	if p.replyTo != "" {
+		m.SetReplyTo(p.replyTo)
+	}
+	for name, value := range p.headers {
+		m.AddHeader(name, value)
+	}
+	if p.sendAt != 0 {
+		m.SetSendAt(int64(p.sendAt))
+	}
+	if p.noTracking {
+		m.AddFilter("opentrack", "enable", 0)
+		m.AddFilter("clicktrack", "enable", 0)
+	}
+	for _, af := range p.attFilenames {
 		f, err := os.Open(af)
 		if err != nil {
 			log.Errorf("Failed to open attachment file %q", af)
-			log.Fatal(err)
+			fail(exitUsage, err)
 		}
-		defer f.Close()
-		m.AddAttachment(af, f)
+		m.AddAttachment(filepath.Base(af), f)
+		m.SetAttachmentType(filepath.Base(af), withCharset(attachmentType(af), p.attCharset))
+		f.Close()
 	}
-	if r := sg.Send(m); r == nil {
-		log.Info("Email sent!")
-	} else {
-		log.Error(r)
+	for _, a := range p.inlineAtts {
+		m.AddAttachmentFromStream(a.name, string(a.content))
+		if a.contentType != "" {
+			m.SetAttachmentType(a.name, withCharset(a.contentType, p.attCharset))
+		}
+		if a.contentID != "" {
+			m.AddContentID(a.name, a.contentID)
+		}
 	}
+	return m
 }
 
-func sendV3(apiKey, from string, tos, ccs []string,
-	subject, htmlContent, plainTextContent, templateID string, subs []string,
-	attFilenames []string) {
+func sendV2(username, password string, p *sendParams) {
+	if err := deliverV2(username, password, p); err != nil {
+		fail(errorExitCode(err), err)
+	}
+}
 
-	if debug {
-		log.Infof("HTML Content: %s", htmlContent)
-		log.Infof("Plain Text Content: %s", plainTextContent)
+// Sends the message using SendGrid v2 API.
+func deliverV2(username, password string, p *sendParams) error {
+	if onBehalfOf != "" {
+		log.Warn("SendGrid v2 API doesn't support sending on behalf of a subuser, ignoring --on-behalf-of.")
+	}
+	if p.sandbox {
+		log.Warn("SendGrid v2 API doesn't support the sandbox mode, ignoring --sandbox.")
+	}
+	if p.trackingSettings != nil {
+		log.Warn("SendGrid v2 API doesn't support the tracking settings of the message spec, ignoring them.")
+	}
+	if p.dumpCurl {
+		log.Warn("--dump-curl supports only SendGrid v3 API, ignoring it.")
 	}
+	sg := v2.NewSendGridClient(username, password)
+	sg.Client = &http.Client{
+		Transport: newTransport(),
+		Timeout:   5 * time.Second,
+	}
+	if timeout > 0 {
+		sg.Client.Timeout = timeout
+	}
+	sg.APIMail = apiHost + "/api/mail.send.json?"
+	sg.UserAgent = userAgent
+	m := newV2Mail(p)
+	err := sg.Send(m)
+	if sg.StatusCode != 0 {
+		observeResponse(&rest.Response{StatusCode: sg.StatusCode})
+	}
+	if err != nil {
+		return err
+	}
+	printSummary(p, nil)
+	return nil
+}
+
+// Returns the sender of the message.
+func senderAddress(p *sendParams) *mail.Email {
+	if p.rawFrom != nil {
+		return p.rawFrom
+	}
+	return createAddress(p.from)
+}
+
+// Creates SendGrid v3 message
+func newV3Message(p *sendParams) *mail.SGMailV3 {
+	htmlContent, plainTextContent := p.htmlContent, p.plainTextContent
 
-	toAddresses := make([]*mail.Email, len(tos))
-	for i, toRaw := range tos {
+	toAddresses := make([]*mail.Email, len(p.tos))
+	for i, toRaw := range p.tos {
 		toAddresses[i] = createAddress(toRaw)
 	}
 
-	ccAddresses := make([]*mail.Email, len(ccs))
-	for i, ccRaw := range ccs {
+	ccAddresses := make([]*mail.Email, len(p.ccs))
+	for i, ccRaw := range p.ccs {
 		ccAddresses[i] = createAddress(ccRaw)
 	}
 
@@ -241,182 +1245,276 @@ func sendV3(apiKey, from string, tos, ccs []string,
 		htmlContent = "<pre>" + plainTextContent + "</pre>"
 	}
 	message := mail.NewSingleEmail(
-		createAddress(from), subject, toAddresses[0], plainTextContent, htmlContent)
-	if len(toAddresses) > 1 {
+		senderAddress(p), p.subject, toAddresses[0], plainTextContent, htmlContent)
+	if p.separate {
+		for _, a := range toAddresses[1:] {
+			personalization := mail.NewPersonalization()
+			personalization.AddTos(a)
+			message.AddPersonalizations(personalization)
+		}
+		for _, personalization := range message.Personalizations {
+			if subject, ok := p.subjects[personalization.To[0].Address]; ok {
+				personalization.Subject = subject
+			}
+			if sendAt, ok := p.sendAts[personalization.To[0].Address]; ok {
+				personalization.SetSendAt(sendAt)
+			}
+		}
+	} else if len(toAddresses) > 1 {
 		message.Personalizations[0].AddTos(toAddresses[1:]...)
 	}
 	if len(ccAddresses) > 0 {
 		message.Personalizations[0].AddCCs(ccAddresses...)
 	}
+	for _, bccRaw := range p.bccs {
+		message.Personalizations[0].AddBCCs(createAddress(bccRaw))
+	}
+	if p.replyTo != "" {
+		message.SetReplyTo(createAddress(p.replyTo))
+	}
+	for name, value := range p.headers {
+		message.SetHeader(name, value)
+	}
+	if p.sendAt != 0 {
+		message.SetSendAt(p.sendAt)
+	}
+	if p.batchID != "" {
+		message.SetBatchID(p.batchID)
+	}
+	if p.sandbox {
+		message.SetMailSettings(mail.NewMailSettings().SetSandboxMode(mail.NewSetting(true)))
+	}
+	if p.trackingSettings != nil || p.noTracking {
+		settings := mail.NewTrackingSettings()
+		if p.trackingSettings != nil {
+			*settings = *p.trackingSettings
+		}
+		if p.noTracking {
+			settings.SetOpenTracking(mail.NewOpenTrackingSetting().SetEnable(false)).
+				SetClickTracking(mail.NewClickTrackingSetting().SetEnable(false))
+		}
+		message.SetTrackingSettings(settings)
+	}
 
-	for _, attFilename := range attFilenames {
-		b, err := ioutil.ReadFile(attFilename)
+	var files, inline []*mail.Attachment
+	for _, attFilename := range p.attFilenames {
+		content, err := encodeFile(attFilename)
 		if err != nil {
 			log.Errorf("Failed to read the attachment %q", attFilename)
-			log.Fatal(err)
+			fail(exitUsage, err)
 		}
 		a := mail.NewAttachment()
-		a.SetType(mime.TypeByExtension(attFilename))
+		a.SetType(withCharset(attachmentType(attFilename), p.attCharset))
 		a.SetDisposition("attachment")
 		a.SetFilename(attFilename)
-		a.SetContent(base64.StdEncoding.EncodeToString(b))
-		message.AddAttachment(a)
+		a.SetContent(content)
+		files = append(files, a)
 		if debug {
 			log.Debugf("Adding the atttachmetn %q", attFilename)
 		}
 	}
 
-	if templateID != "" {
-		message.SetTemplateID(templateID)
-		for _, sub := range subs {
+	for _, ia := range p.inlineAtts {
+		a := mail.NewAttachment()
+		a.SetType(withCharset(ia.contentType, p.attCharset))
+		if ia.contentID != "" {
+			a.SetDisposition("inline")
+			a.SetContentID(ia.contentID)
+		} else {
+			a.SetDisposition("attachment")
+		}
+		a.SetFilename(ia.name)
+		a.SetContent(base64.StdEncoding.EncodeToString(ia.content))
+		inline = append(inline, a)
+	}
+	for _, a := range orderAttachments(p.attOrder, files, inline) {
+		message.AddAttachment(a)
+	}
+
+	if p.templateID != "" {
+		message.SetTemplateID(p.templateID)
+		for _, sub := range p.subs {
 			parts := strings.SplitN(sub, "=", 2)
 			if len(parts) != 2 {
-				log.Fatalf("Incorrect substitution: %s", subs)
+				failf(exitUsage, "Incorrect substitution: %s", sub)
 			}
 			if debug {
 				log.Debugf("Added substitution %q with the value %q", parts[0], parts[1])
 			}
-			message.Personalizations[0].SetSubstitution("[%"+parts[0]+"%]", parts[1])
+			for _, personalization := range message.Personalizations {
+				personalization.SetSubstitution("[%"+parts[0]+"%]", parts[1])
+			}
 		}
 	}
+	return message
+}
 
-	rest.DefaultClient.HTTPClient.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
-	client := sendgrid.NewSendClient(apiKey)
-	response, err := client.Send(message)
-	if err != nil {
+func sendV3(apiKey string, p *sendParams) {
+	if err := deliverV3(apiKey, p); err != nil {
 		log.Error("Failed to send the message.")
-		log.Error(err)
-	} else {
-		if verbose || debug {
-			log.Info("Status Code:", response.StatusCode)
-			log.Info("Response Body:", response.Body)
-			log.Info("Response Headers:")
-			log.Info("=================")
-			for k, v := range response.Headers {
-				log.Infof("%s: %v", k, v)
-			}
-		}
+		fail(errorExitCode(err), err)
 	}
 }
 
-func newMultipPartForm(urlStr string, values url.Values, filenames []string) (*http.Request, error) {
+// Sends the message using SendGrid v3 API.
+func deliverV3(apiKey string, p *sendParams) error {
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-
-	for _, fn := range filenames {
-		file, err := os.Open(fn)
-		defer file.Close()
-
-		if err != nil {
-			return nil, err
-		}
-		fileContents, err := ioutil.ReadAll(file)
-		if err != nil {
-			return nil, err
-		}
-		fi, err := file.Stat()
-		if err != nil {
-			return nil, err
-		}
-		part, err := writer.CreateFormFile(fn, fi.Name())
-		if err != nil {
-			return nil, err
-		}
-		part.Write(fileContents)
+	if debug {
+		log.Infof("HTML Content: %s", p.htmlContent)
+		log.Infof("Plain Text Content: %s", p.plainTextContent)
 	}
 
-	for key, vals := range values {
-		if len(vals) == 1 {
-			_ = writer.WriteField(key, vals[0])
-		} else {
-			for _, v := range vals {
-				_ = writer.WriteField(key+"[]", v)
-			}
-		}
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	request, err := mailSendRequest(apiKey, p)
+	if err != nil {
+		return err
 	}
-
-	err := writer.Close()
+	if p.dumpCurl {
+		fmt.Fprintln(stdout, curlCommand(request))
+	}
+	response, err := doAPIRequest(request)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	printSummary(p, response.Headers["X-Message-Id"])
+	return nil
+}
 
-	return http.NewRequest("POST", urlStr, body)
+// Creates the v3 API request sending the message.
+func mailSendRequest(apiKey string, p *sendParams) (rest.Request, error) {
+	body, err := requestBody(p)
+	if err != nil {
+		return rest.Request{}, err
+	}
+	request := newAPIRequest(apiKey, rest.Post, "/v3/mail/send")
+	request.Body = body
+	if len(p.tos) == 1 {
+		if subuser, ok := p.subusers[createAddress(p.tos[0]).Address]; ok {
+			request.Headers["on-behalf-of"] = subuser
+		}
+	}
+	return request, nil
 }
 
-func addressToLists(emails []*mail.Email) ([]string, []string) {
-	addresses := make([]string, len(emails))
-	addressNames := make([]string, len(emails))
-	for i, e := range emails {
-		addresses[i] = e.Address
-		addressNames[i] = e.Name
+// Prints the one-line summary of the sent message unless in the quiet mode.
+func printSummary(p *sendParams, messageIDs []string) {
+	if quiet || p.quiet {
+		return
 	}
-	return addresses, addressNames
+	summary := fmt.Sprintf("Sent to %d recipient(s): %q", len(p.tos)+len(p.ccs)+len(p.bccs), p.subject)
+	if len(messageIDs) > 0 {
+		summary += " (message ID: " + messageIDs[0] + ")"
+	}
+	fmt.Fprintln(stdout, summary)
+}
+
+// Personalization with the dynamic template data (not supported by the mail helper)
+type personalizationWithData struct {
+	*mail.Personalization
+	DynamicTemplateData map[string]interface{} `json:"dynamic_template_data,omitempty"`
 }
 
-func _sendV2(username, password string, m *mail.SGMailV3) {
+// Message with the dynamic template data in the personalizations and
+// the reply-to list, both unsupported by the mail helper
+type messageWithData struct {
+	*mail.SGMailV3
+	Personalizations []personalizationWithData `json:"personalizations,omitempty"`
+	ReplyToList      []*mail.Email             `json:"reply_to_list,omitempty"`
+}
 
-	values := url.Values{
-		"api_user": {username},
-		"api_key":  {password},
-		"subject":  {m.Subject},
-		"from":     {m.From.Address},
-		"fromname": {m.From.Name},
+// Builds the v3 mail/send request body of the message adding the dynamic
+// template data to every personalization and the reply-to list.
+func requestBody(p *sendParams) ([]byte, error) {
+	message := newV3Message(p)
+	if len(p.templateData) == 0 && len(p.replyToList) == 0 {
+		return json.Marshal(message)
 	}
-	values["to"], values["toname"] = addressToLists(m.Personalizations[0].To)
-	cc := m.Personalizations[0].CC
-	if len(cc) > 0 {
-		values["cc"], values["ccname"] = addressToLists(cc)
+	m := messageWithData{SGMailV3: message}
+	for _, personalization := range message.Personalizations {
+		m.Personalizations = append(m.Personalizations, personalizationWithData{personalization, p.templateData})
+	}
+	for _, raw := range p.replyToList {
+		m.ReplyToList = append(m.ReplyToList, createAddress(raw))
+	}
+	return json.Marshal(m)
+}
+
+// Reads template substitutions from a file with "key=value" lines.
+// Blank lines and lines starting with "#" are ignored.
+func readSubstitutions(filename string) ([]string, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
 	}
-	for _, c := range m.Content {
-		if c.Type == "text/html" && c.Value != "" {
-			values.Add("html", c.Value)
+	var subs []string
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
-		if c.Type == "text/plain" && c.Value != "" {
-			values.Add("text", c.Value)
+		if !strings.Contains(line, "=") {
+			return nil, fmt.Errorf("%s:%d: incorrect substitution %q, expected key=value", filename, i+1, line)
 		}
+		subs = append(subs, line)
 	}
+	return subs, nil
+}
 
-	filenames := make([]string, len(m.Attachments))
-	for i, a := range m.Attachments {
-		filenames[i] = a.Filename
+// Logs the details of the API response in verbose or debug mode.
+func logResponse(response *rest.Response) {
+	if verbose || debug {
+		log.Info("Status Code:", response.StatusCode)
+		log.Info("Response Body:", response.Body)
+		log.Info("Response Headers:")
+		log.Info("=================")
+		for _, k := range sortedHeaderNames(response.Headers) {
+			log.Infof("%s: %v", k, response.Headers[k])
+		}
 	}
-	// for _, a := range m.Attachments {
-	// 	values["files["+a.Name+"]; filename="+a.Filename+" ;type="+a.Type, a.Content)
-	// }
+}
 
-	request, err := newMultipPartForm("https://api.sendgrid.com/api/mail.send.json", values, filenames)
+// Streams the file content through the base64 encoder in chunks, so that
+// only the encoded content is held in memory as a whole.
+func encodeFile(filename string) (string, error) {
+	f, err := os.Open(filename)
 	if err != nil {
-		log.Error("Failed to create the API request.")
-		log.Fatal(err)
+		return "", err
 	}
+	defer f.Close()
+	var buf strings.Builder
+	if fi, err := f.Stat(); err == nil {
+		buf.Grow(base64.StdEncoding.EncodedLen(int(fi.Size())))
+	}
+	encoder := base64.NewEncoder(base64.StdEncoding, &buf)
+	if _, err := io.CopyBuffer(encoder, f, make([]byte, 32*1024)); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
 
-	// if debug {
-	// 	defer request.Body.Close()
-	// 	body, _ := ioutil.ReadAll(request.Body)
-	// 	log.Debug(string(body))
-	// }
+// attachmentType guesses the content type of the attachment by its extension.
+func attachmentType(filename string) string {
+	if t := mime.TypeByExtension(filepath.Ext(filename)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(request)
-	if err != nil {
-		log.Error("Failed to send the email.")
-		log.Fatal(err)
-	} else {
-		var bodyContent []byte
-		log.Infof("Status Code: %d", resp.StatusCode)
-		if debug {
-			log.Info("Headers:")
-			log.Info("========")
-			for k, v := range resp.Header {
-				log.Infof("%s:\t%v", k, v)
-			}
-			resp.Body.Read(bodyContent)
-			resp.Body.Close()
-			log.Info(bodyContent)
-		}
+// Declares the charset in the text content type, eg, "text/csv; charset=utf-8".
+// The other content types are returned as is.
+func withCharset(contentType, charset string) string {
+	if charset == "" {
+		return contentType
 	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "text/") {
+		return contentType
+	}
+	params["charset"] = charset
+	return mime.FormatMediaType(mediaType, params)
 }
 
 // RootCmd represents the base command when called without any subcommands
@@ -436,9 +1534,25 @@ in this case the HTML content will get converted into the plain text version and
 sendgrid-cli -k API-KEY -t recepient@domain.net -f sender@foo.bar -s "The subject" -b FILENAME.html
 sendgrid-cli -k API-KEY -t recepient@domain.net -f sender@foo.bar -s "The subject" -T TEMPLATE-ID -S "name=John Doe" -S "price=$42"
 
+With --separate and --delay or --rate every recipient gets an individual message
+sent at the throttled pace, eg, for IP warmup:
+
+sendgrid-cli -k API-KEY --recipients recipients.csv --separate --rate 30 -s "The subject" -b FILENAME.html
+
 Instead of -k API-KEY you can user --user/-U with --password/-P.
+If both are given, the API key wins (use --strict to reject such ambiguous usage).
+
+Exit codes:
+  0 - the message was sent successfully;
+  2 - incorrect usage or invalid input;
+  3 - SendGrid API rejected the request (4xx);
+  4 - network failure or SendGrid API server error (5xx);
+  5 - blacklist-check found a blocklist listing;
+  130 - interrupted with Ctrl-C (the in-flight send is finished).
 `,
-	Run: send,
+	// The positional arguments are the message content rather than subcommands:
+	Args: cobra.ArbitraryArgs,
+	Run:  send,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -446,7 +1560,7 @@ Instead of -k API-KEY you can user --user/-U with --password/-P.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		exit(exitUsage)
 	}
 }
 
@@ -458,28 +1572,200 @@ func init() {
 	// will be global for your application.
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "",
 		"config file (default is $HOME/.sendgrid-cli.yaml)")
+	RootCmd.PersistentFlags().BoolVar(&noConfig, "no-config", false,
+		"Ignore the config file and the environment variables, use only the given flags.")
+	RootCmd.PersistentFlags().String("env", "",
+		"Environment, eg, staging, whose section \"environments.<name>\" of the config file overrides the settings.")
 
 	RootCmd.PersistentFlags().BoolP("debug", "d", false, "Show full stack trace on error.")
 	RootCmd.PersistentFlags().BoolP("verbose", "V", false, "Show more verbose details.")
+	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "Don't print the summary of the sent message.")
+	RootCmd.PersistentFlags().Bool("redact-recipients", false,
+		"Mask the local parts of the addresses in the log output, eg, j***@example.com (the messages are sent to the real addresses).")
+	RootCmd.PersistentFlags().Bool("log-syslog", false, "Also send the log messages to syslog tagged with the program name.")
+	RootCmd.PersistentFlags().String("syslog-address", "",
+		"Syslog used with --log-syslog as network:host:port, eg, udp:localhost:514 (default is the local one).")
+	RootCmd.PersistentFlags().StringP("output", "o", outputTable,
+		"Output format of the result: table, json or csv (where applicable).")
 	RootCmd.PersistentFlags().BoolP("json", "j", false, "Print result as JSON (where applicable).")
+	RootCmd.PersistentFlags().MarkDeprecated("json", "use --output json instead")
+	RootCmd.PersistentFlags().Bool("pretty", false, "Indent the JSON output with two spaces.")
+	RootCmd.PersistentFlags().String("min-tls", "1.2",
+		"Minimal TLS version of the connection to the API (1.2 or 1.3).")
+	RootCmd.PersistentFlags().String("user-agent", userAgent, "User-Agent header of the API requests.")
+	RootCmd.PersistentFlags().Duration("timeout", 0, "Timeout of the API requests, eg, 10s (0 - the default).")
 	RootCmd.PersistentFlags().StringP("key", "k", "",
 		"SendGrid API Key (can set using environment variable SENDGRID_API_KEY).")
+	RootCmd.PersistentFlags().String("on-behalf-of", "",
+		"Make the API requests on behalf of the subuser (requires the parent account API key).")
+	RootCmd.PersistentFlags().Bool("strict", false,
+		"Treat the ambiguous usage, eg, both API key and username/password given, too long subject "+
+			"or the recipient domains without MX records as an error.")
+	RootCmd.PersistentFlags().String("api-version", "",
+		"SendGrid API version used for sending: 2 or 3 (default is v3 if the API key is given, otherwise v2).")
 	RootCmd.PersistentFlags().StringP("user", "U", "", "Sendgrid user name.")
 	RootCmd.PersistentFlags().StringP("password", "P", "", "Sendgrid user password.")
-	RootCmd.PersistentFlags().StringP("from", "f", "sendgrid-cli@nowitworks.eu", "FROM address.")
+	RootCmd.PersistentFlags().VarP(fromFlag, "from", "f",
+		"FROM address (defaults to SENDGRID_FROM or git config user.email if set), "+
+			"repeat to rotate the addresses between the messages.")
+	RootCmd.PersistentFlags().String("from-rotation", fromRoundRobin,
+		"Assignment of the multiple FROM addresses to the messages: round-robin or random.")
+	RootCmd.PersistentFlags().Bool("from-raw", false,
+		"Use --from-name and --from-addr verbatim as the sender instead of parsing --from.")
+	RootCmd.PersistentFlags().String("from-name", "", "FROM name used with --from-raw.")
+	RootCmd.PersistentFlags().String("from-name-fallback", "",
+		"FROM display name used if the FROM address is given without one.")
+	RootCmd.PersistentFlags().String("from-addr", "", "FROM address used with --from-raw.")
+	RootCmd.PersistentFlags().String("reply-to", "", "REPLY-TO address.")
+	RootCmd.PersistentFlags().Bool("reply-to-from", false, "Use the FROM address as the REPLY-TO address.")
+	RootCmd.PersistentFlags().String("reply-to-file", "",
+		"File with REPLY-TO addresses, one per line, merged with --reply-to (multiple addresses require API key).")
 	RootCmd.PersistentFlags().StringArrayP("to", "t", []string{}, "TO address (can be multiple).")
+	RootCmd.PersistentFlags().String("recipients", "",
+		"CSV file with the header row and the TO recipients, one per row (columns: email, required, "+
+			"and the optional name, reply_to, subject overriding the subject, send_at scheduling the delivery "+
+			"and subuser sending on behalf of the subuser).")
+	RootCmd.PersistentFlags().String("map", "",
+		"Mapping of the --recipients columns to the CSV headers, eg, email=Email,name=FullName.")
+	RootCmd.PersistentFlags().String("recipient-filter", "",
+		"Shell command filtering the TO recipients given one per line on its stdin and printed on its stdout.")
+	RootCmd.PersistentFlags().Bool("separate", false,
+		"Send a separate personalization to every TO recipient, so they don't see each other.")
 	RootCmd.PersistentFlags().StringArray("cc", []string{}, "CC address (can be multiple).")
+	RootCmd.PersistentFlags().StringArray("bcc", []string{}, "BCC address (can be multiple).")
+	RootCmd.PersistentFlags().String("to-file", "", "File with TO addresses, one per line.")
+	RootCmd.PersistentFlags().String("cc-file", "", "File with CC addresses, one per line.")
+	RootCmd.PersistentFlags().String("bcc-file", "", "File with BCC addresses, one per line.")
+	RootCmd.PersistentFlags().Bool("dedupe-across-lists", false,
+		"Keep the address only in the highest-priority list (TO > CC > BCC).")
+	RootCmd.PersistentFlags().Bool("cc-self", false, "Add the FROM address to the CC list.")
+	RootCmd.PersistentFlags().Bool("bcc-self", false, "Add the FROM address to the BCC list.")
+	RootCmd.PersistentFlags().Bool("abort-on-suppressed", false,
+		"Abort if any recipient is on the bounce, block, spam report or unsubscribe list (requires API key).")
+	RootCmd.PersistentFlags().Bool("skip-suppressed", false,
+		"Skip the recipients on the bounce, block, spam report or unsubscribe list (requires API key).")
+	RootCmd.PersistentFlags().Int("max-recipients", 0,
+		"Abort if the total number of To, CC and BCC recipients exceeds the limit (0 - unlimited).")
+	RootCmd.PersistentFlags().Int("batch-size", 0,
+		"Split the TO recipients across the API calls of at most N recipients each (0 - single call).")
+	RootCmd.PersistentFlags().Duration("delay", 0, "Pause between the API calls, eg, 1s or 500ms.")
+	RootCmd.PersistentFlags().Int("rate", 0, "Maximum number of the API calls per minute (0 - unlimited).")
+	RootCmd.PersistentFlags().Bool("rate-limit-aware", false,
+		"Pause the API calls until the rate limit resets once SendGrid reports no calls left.")
+	RootCmd.PersistentFlags().Bool("fail-fast", false,
+		"Abort the run with multiple API calls on the first failure.")
+	RootCmd.PersistentFlags().Bool("continue-on-error", true,
+		"Attempt all the API calls of the run and summarize the failures at the end (default).")
+	RootCmd.PersistentFlags().Int("retries", 0,
+		"Retry the API call failed with a network or SendGrid API error (5xx or 429) up to N times.")
+	RootCmd.PersistentFlags().Duration("retry-backoff", time.Second,
+		"Pause before the first retry, doubled on every next one.")
+	RootCmd.PersistentFlags().String("retry-on-body-match", "",
+		"Also retry the API call if the response body matches the regular expression, regardless of the status code.")
+	RootCmd.PersistentFlags().String("summary-file", "",
+		"File the JSON report of the run with the result of every API call gets written to.")
+	RootCmd.PersistentFlags().Int("repeat", 1,
+		"Send the message N times, eg, for load testing (asks for confirmation above 100).")
 	RootCmd.PersistentFlags().StringArrayP("att", "a", []string{}, "Attachment (can be multiple).")
+	RootCmd.PersistentFlags().String("attachment-charset", "",
+		"Charset declared in the content type of the text attachments, eg, utf-8.")
+	RootCmd.PersistentFlags().Bool("auto-inline", false,
+		"Embed the local images referenced in the HTML body as inline attachments.")
+	RootCmd.PersistentFlags().Int("auto-inline-threshold", 100*1024,
+		"Maximum size in bytes of the image embedded with --auto-inline.")
+	RootCmd.PersistentFlags().String("cid-strategy", cidIndex,
+		"CID generation of the images embedded with --auto-inline: filename, uuid or index.")
+	RootCmd.PersistentFlags().StringArray("vcard", []string{}, "vCard file attached as text/vcard (can be multiple).")
+	RootCmd.PersistentFlags().StringArray("att-inline", []string{},
+		"Attachment given inline as name:type:content, the content '@-' is read from stdin (can be multiple).")
+	recordAttachmentOrder(RootCmd.PersistentFlags(), "att", "att-inline")
+	RootCmd.PersistentFlags().String("from-file", "",
+		"Message spec JSON file with the recipients, subject, body files, etc. (the flags take precedence).")
 	RootCmd.PersistentFlags().StringP("subject", "s", "", "Email subject.")
+	RootCmd.PersistentFlags().Int("max-subject-length", 998,
+		"Maximum subject length in characters, longer subjects are warned about or rejected with --strict (0 - unlimited).")
+	RootCmd.PersistentFlags().String("subject-prefix", "",
+		"Prefix prepended to the subject unless it is already there, eg, '[STAGING] '.")
+	RootCmd.PersistentFlags().String("content", "",
+		"Body file name, .html/.htm is used as HTML body and .txt as plain-text body.")
+	RootCmd.PersistentFlags().StringArray("personalization-subject", nil,
+		"Per-recipient subject given as address=subject, used with --separate (can be multiple).")
 	RootCmd.PersistentFlags().StringP("html", "b", "", "HTML body file name.")
 	RootCmd.PersistentFlags().StringP("plain", "p", "", "Plain-text body file name.")
+	RootCmd.PersistentFlags().String("html-cmd", "",
+		"Command producing the HTML body on its standard output (run with the shell).")
+	RootCmd.PersistentFlags().String("plain-cmd", "",
+		"Command producing the plain-text body on its standard output (run with the shell).")
+	RootCmd.PersistentFlags().String("body", "",
+		"Markdown body file name, the HTML and plain-text bodies are rendered from it (see --render).")
+	RootCmd.PersistentFlags().String("render", "both",
+		"Body parts rendered from --body: html, plain or both.")
+	RootCmd.PersistentFlags().Int("max-body-size", 30*1024*1024,
+		"Maximum size in bytes of the HTML or plain-text body (0 - unlimited).")
+	RootCmd.PersistentFlags().Bool("gzip", false,
+		"The body files are gzipped (assumed for the files ending with .gz).")
+	RootCmd.PersistentFlags().Bool("trim", false,
+		"Strip the leading and trailing whitespace of the body files.")
+	RootCmd.PersistentFlags().Bool("plain-pretty-tables", true,
+		"Render the HTML tables as ASCII tables in the plain-text conversion of the HTML body.")
+	RootCmd.PersistentFlags().String("plain-encoding", plainEncodingNone,
+		"Prepare the plain-text part for quoted-printable or base64 encoding normalizing the line endings "+
+			"and wrapping the long lines (SendGrid picks the encoding itself): quoted-printable, base64 or none.")
+	RootCmd.PersistentFlags().String("plain-text", "",
+		"Inline plain-text content used instead of the conversion of the HTML body.")
 	RootCmd.PersistentFlags().StringP("template-id", "T", "", "Sendgrid template ID.")
+	RootCmd.PersistentFlags().String("template-name", "", "Sendgrid template name looked up instead of --template-id.")
+	RootCmd.PersistentFlags().Bool("verify-template", false,
+		"Verify that the template exists and matches --sub (legacy) or --data (dynamic) before sending the message.")
+	RootCmd.PersistentFlags().Bool("inline-css", false,
+		"Move the rules of the <style> blocks of the HTML body to the style attributes of the elements.")
+	RootCmd.PersistentFlags().Bool("sanitize", false,
+		"Strip scripts, styles, forms, embedded objects and event handlers from the HTML body.")
+	RootCmd.PersistentFlags().String("data", "",
+		"Dynamic template data as JSON object or @FILENAME of the JSON file.")
+	RootCmd.PersistentFlags().Bool("render-local", false,
+		"Render the body as Go text/template with --data locally instead of using SendGrid template.")
+	RootCmd.PersistentFlags().Bool("check-sender", false,
+		"Warn if the FROM address isn't a verified single sender (requires API key).")
+	RootCmd.PersistentFlags().Bool("check-dns", false,
+		"Warn if the SendGrid DKIM records (s1/s2._domainkey) of the FROM domain are missing.")
+	RootCmd.PersistentFlags().Bool("check-mx", false,
+		"Warn if the recipient domains have no MX records (abort with --strict).")
+	RootCmd.PersistentFlags().BoolP("interactive", "i", false,
+		"Prompt for the missing recipients, subject and body, and confirm before sending.")
+	RootCmd.PersistentFlags().String("send-at", "",
+		"Schedule the delivery at the UNIX timestamp or RFC 3339 time (at most --max-send-at-window ahead).")
+	RootCmd.PersistentFlags().Duration("max-send-at-window", 72*time.Hour,
+		"How far ahead the delivery can be scheduled, 72 hours unless the SendGrid plan allows more.")
+	RootCmd.PersistentFlags().Bool("dry-run", false,
+		"Validate and print the message without sending it.")
+	RootCmd.PersistentFlags().Bool("dump-curl", false,
+		"Print the curl command making the v3 API request, the API key replaced with $SENDGRID_API_KEY "+
+			"(instead of the message with --dry-run).")
+	RootCmd.PersistentFlags().Bool("sandbox", false,
+		"Send the message in the sandbox mode: SendGrid validates it, but doesn't deliver it (v3 API only).")
+	RootCmd.PersistentFlags().Bool("require-tls", false,
+		"Request TLS on the delivery with the "+requireTLSHeader+" header (SendGrid has no such mail setting).")
+	RootCmd.PersistentFlags().String("message-id", "",
+		"Message-ID header, eg, <order-42@example.com>, for the deduplication on the recipient side.")
+	RootCmd.PersistentFlags().Bool("no-tracking", false, "Disable both open and click tracking.")
+	RootCmd.PersistentFlags().StringArrayP("header", "H", nil,
+		"Custom header, eg, --header 'X-Campaign: spring' (can be multiple).")
+	RootCmd.PersistentFlags().String("header-file", "",
+		"File with custom headers, one 'Name: Value' per line.")
+	RootCmd.PersistentFlags().String("header-json", "",
+		"Custom headers as JSON object or @FILENAME of the JSON file.")
 	RootCmd.PersistentFlags().StringArrayP("sub", "S", nil,
 		"Template paramter substitution, eg, --sub ':name=Jhon Doe'")
+	RootCmd.PersistentFlags().String("sub-file", "",
+		"File with template paramter substitutions, one key=value per line.")
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	if noConfig {
+		return
+	}
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
@@ -487,8 +1773,7 @@ func initConfig() {
 		// Find home directory.
 		home, err := homedir.Dir()
 		if err != nil {
-			log.Error(err)
-			os.Exit(1)
+			fail(exitUsage, err)
 		}
 
 		// Search config in home directory with name ".sendgrid-cli" (without extension).
@@ -504,6 +1789,14 @@ func initConfig() {
 	}
 }
 
+// Returns the environment variable, empty with --no-config.
+func getenv(name string) string {
+	if noConfig {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
 func flagString(cmd *cobra.Command, name string) string {
 	return cmd.Flag(name).Value.String()
 }
@@ -511,7 +1804,7 @@ func flagString(cmd *cobra.Command, name string) string {
 func flagStringSlice(cmd *cobra.Command, name string) (val []string) {
 	val, err := cmd.Flags().GetStringSlice(name)
 	if err != nil {
-		log.Fatal(err)
+		fail(exitUsage, err)
 	}
 	return
 }
@@ -519,7 +1812,7 @@ func flagStringSlice(cmd *cobra.Command, name string) (val []string) {
 func flagStringArray(cmd *cobra.Command, name string) (val []string) {
 	val, err := cmd.Flags().GetStringArray(name)
 	if err != nil {
-		log.Fatal(err)
+		fail(exitUsage, err)
 	}
 	return
 }
@@ -527,7 +1820,7 @@ func flagStringArray(cmd *cobra.Command, name string) (val []string) {
 func flagBool(cmd *cobra.Command, name string) (val bool) {
 	val, err := cmd.Flags().GetBool(name)
 	if err != nil {
-		log.Fatal(err)
+		fail(exitUsage, err)
 	}
 	return
 }
@@ -535,20 +1828,84 @@ func flagBool(cmd *cobra.Command, name string) (val bool) {
 func flagInt(cmd *cobra.Command, name string) (val int) {
 	val, err := cmd.Flags().GetInt(name)
 	if err != nil {
-		log.Fatal(err)
+		fail(exitUsage, err)
+	}
+	return
+}
+
+func flagDuration(cmd *cobra.Command, name string) (val time.Duration) {
+	val, err := cmd.Flags().GetDuration(name)
+	if err != nil {
+		fail(exitUsage, err)
 	}
 	return
 }
 
+// Reads the addresses from the file given with the flag.
+func flagAddresses(cmd *cobra.Command, name string) []string {
+	filename := flagString(cmd, name)
+	if filename == "" {
+		return nil
+	}
+	addresses, err := readAddresses(filename)
+	if err != nil {
+		log.Errorf("Failed to read the addresses from %q", filename)
+		fail(exitUsage, err)
+	}
+	return addresses
+}
+
+// Returns SendGrid API key given with --key option or the environment variable SENDGRID_API_KEY.
+func apiKeyFlag(cmd *cobra.Command) string {
+	if apiKey := flagString(cmd, "key"); apiKey != "" {
+		return apiKey
+	}
+	apiKey := getenv("SENDGRID_API_KEY")
+	if apiKey == "" {
+		fail(exitUsage, "Missing Sendgrid API key. Use --key option or set SENDGRID_API_KEY.")
+	}
+	return apiKey
+}
+
 func debugCmd(cmd *cobra.Command) {
+	applyConfig(cmd)
 	debug = flagBool(cmd, "debug")
 	verbose = flagBool(cmd, "verbose")
+	quiet = flagBool(cmd, "quiet")
+	onBehalfOf = flagString(cmd, "on-behalf-of")
+	timeout = flagDuration(cmd, "timeout")
+	userAgent = flagString(cmd, "user-agent")
+	prettyTables = flagBool(cmd, "plain-pretty-tables")
+	prettyJSON = flagBool(cmd, "pretty")
+	redactingRecipients = flagBool(cmd, "redact-recipients")
+	if maxSendAtWindow = flagDuration(cmd, "max-send-at-window"); maxSendAtWindow <= 0 {
+		fail(exitUsage, "--max-send-at-window should be positive.")
+	}
+	if flagBool(cmd, "log-syslog") && !loggingToSyslog {
+		hook, err := newSyslogHook(flagString(cmd, "syslog-address"))
+		if err != nil {
+			failf(exitUsage, "Failed to set up logging to syslog: %v", err)
+		}
+		log.AddHook(hook)
+		loggingToSyslog = true
+	}
+	rest.DefaultClient.HTTPClient.Timeout = timeout
+	if v, ok := tlsVersions[flagString(cmd, "min-tls")]; ok {
+		minTLS = v
+	} else {
+		failf(exitUsage, "Unsupported minimal TLS version %q, use 1.2 or 1.3.", flagString(cmd, "min-tls"))
+	}
 
 	if debug {
 		log.SetLevel(log.DebugLevel)
 		title := fmt.Sprintf("Command %q called with flags:", cmd.Name())
 		log.Info(title)
 		log.Info(strings.Repeat("=", len(title)))
-		cmd.DebugFlags()
+		if redactingRecipients {
+			// DebugFlags prints past the logger and its redacting hook
+			cmd.Flags().Visit(func(f *pflag.Flag) { log.Infof("--%s %s", f.Name, f.Value) })
+		} else {
+			cmd.DebugFlags()
+		}
 	}
 }
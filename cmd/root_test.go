@@ -0,0 +1,1058 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	v2 "sendgrid-cli/sendgrid"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+func TestV2Attachments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jpg := filepath.Join(dir, "photo.jpg")
+	txt := filepath.Join(dir, "notes.txt")
+	ioutil.WriteFile(jpg, []byte("JPEG"), 0600)
+	ioutil.WriteFile(txt, []byte("NOTES"), 0600)
+
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Failed to parse multipart form: %v", err)
+			return
+		}
+		for field, contentType := range map[string]string{
+			"files[photo.jpg]": "image/jpeg",
+			"files[notes.txt]": "text/plain; charset=utf-8",
+			"files[data.csv]":  "text/csv",
+		} {
+			fhs := r.MultipartForm.File[field]
+			if len(fhs) != 1 {
+				t.Errorf("Expected the attachment field %q, got: %v", field, r.MultipartForm.File)
+				continue
+			}
+			if ct := fhs[0].Header.Get("Content-Type"); ct != contentType {
+				t.Errorf("Expected %q content type %q, got %q", field, contentType, ct)
+			}
+		}
+		if r.FormValue("subject") != "Test" || r.FormValue("to[]") != "to@example.com" || r.FormValue("api_user") != "USER" {
+			t.Errorf("Expected the message fields, got %v", r.MultipartForm.Value)
+		}
+	}))
+	defer fakeServer.Close()
+
+	defer func(h string, q bool) { apiHost, quiet = h, q }(apiHost, quiet)
+	apiHost, quiet = fakeServer.URL, true
+
+	err = deliverV2("USER", "PASSWORD", &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		attFilenames:     []string{jpg, txt},
+		inlineAtts:       []*inlineAttachment{{name: "data.csv", contentType: "text/csv", content: []byte("a,b\n")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSubstitutions(t *testing.T) {
+	f, err := ioutil.TempFile("", "subs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# The template parameters\nname=John Doe\n\nprice=$42\nurl=https://example.com/?a=1&b=2\n")
+	f.Close()
+
+	subs, err := readSubstitutions(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:        "sender@example.com",
+		tos:         []string{"to@example.com"},
+		subject:     "Test",
+		htmlContent: dummyContent,
+		templateID:  "TEMPLATE-ID",
+		subs:        append(subs, "inline=value"),
+	})
+	expected := map[string]string{
+		"[%name%]":   "John Doe",
+		"[%price%]":  "$42",
+		"[%url%]":    "https://example.com/?a=1&b=2",
+		"[%inline%]": "value",
+	}
+	actual := message.Personalizations[0].Substitutions
+	if len(actual) != len(expected) {
+		t.Errorf("Expected %d substitutions, got: %v", len(expected), actual)
+	}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("Expected substitution %q to be %q, got %q", k, v, actual[k])
+		}
+	}
+}
+
+func TestReadSubstitutionsFail(t *testing.T) {
+	f, err := ioutil.TempFile("", "subs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("name=John Doe\nprice\n")
+	f.Close()
+
+	if _, err := readSubstitutions(f.Name()); err == nil {
+		t.Error("readSubstitutions should fail on a line without '='")
+	}
+}
+
+func TestMinTLS(t *testing.T) {
+	fakeServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	fakeServer.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	fakeServer.StartTLS()
+	defer fakeServer.Close()
+	defer func(v uint16, p *x509.CertPool) { minTLS, rootCAs = v, p }(minTLS, rootCAs)
+
+	client := &http.Client{Transport: newTransport()}
+	if resp, err := client.Get(fakeServer.URL); err == nil {
+		resp.Body.Close()
+		t.Error("The connection to the server with an untrusted certificate should be refused")
+	}
+
+	rootCAs = x509.NewCertPool()
+	rootCAs.AddCert(fakeServer.Certificate())
+	minTLS = tlsVersions["1.2"]
+	client = &http.Client{Transport: newTransport()}
+	if resp, err := client.Get(fakeServer.URL); err != nil {
+		t.Errorf("The TLS 1.2 connection should succeed: %v", err)
+	} else {
+		resp.Body.Close()
+	}
+
+	minTLS = tlsVersions["1.3"]
+	client = &http.Client{Transport: newTransport()}
+	if resp, err := client.Get(fakeServer.URL); err == nil {
+		resp.Body.Close()
+		t.Error("The connection with the TLS version lower than --min-tls should be refused")
+	}
+}
+
+func TestReplyToFrom(t *testing.T) {
+	from := "John Doe <john@example.com>"
+	replyTo, err := resolveReplyTo(from, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             from,
+		tos:              []string{"to@example.com"},
+		replyTo:          replyTo,
+		subject:          "Test",
+		plainTextContent: "Test",
+	})
+	if message.ReplyTo == nil || *message.ReplyTo != *message.From {
+		t.Errorf("Expected reply-to %v to match from %v", message.ReplyTo, message.From)
+	}
+
+	if _, err := resolveReplyTo(from, "other@example.com", true); err == nil {
+		t.Error("--reply-to and --reply-to-from should be mutually exclusive")
+	}
+	if replyTo, _ := resolveReplyTo(from, "other@example.com", false); replyTo != "other@example.com" {
+		t.Errorf("Expected the explicit reply-to, got %q", replyTo)
+	}
+}
+
+func TestCheckMaxRecipients(t *testing.T) {
+	tos := []string{"a@example.com", "b@example.com"}
+	ccs := []string{"c@example.com"}
+	bccs := []string{"d@example.com"}
+	if err := checkMaxRecipients(0, tos, ccs, bccs); err != nil {
+		t.Errorf("No limit should be applied by default: %v", err)
+	}
+	if err := checkMaxRecipients(4, tos, ccs, bccs); err != nil {
+		t.Errorf("The number of recipients within the limit should be sent: %v", err)
+	}
+	err := checkMaxRecipients(3, tos, ccs, bccs)
+	if err == nil || !strings.Contains(err.Error(), "--max-recipients 3") {
+		t.Errorf("Exceeding the limit should abort with a clear error, got: %v", err)
+	}
+}
+
+func TestCreateAddressNormalizesDomain(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"a@EXAMPLE.com":               "a@example.com",
+		"Foo@example.com":             "Foo@example.com",
+		"John Doe <John.Doe@Mail.IO>": "John.Doe@mail.io",
+	} {
+		if a := createAddress(raw); a.Address != expected {
+			t.Errorf("Expected %q to be normalized into %q, got %q", raw, expected, a.Address)
+		}
+	}
+	if a := createAddress("John Doe <john@Example.com>"); a.Name != "John Doe" {
+		t.Errorf("The name should be kept intact, got %q", a.Name)
+	}
+}
+
+type exitCode int
+
+// Runs f expecting it to terminate the process with the exit code.
+func expectExit(t *testing.T, code int, f func()) {
+	t.Helper()
+	defer func(e func(int)) { exit = e }(exit)
+	exit = func(c int) { panic(exitCode(c)) }
+	defer func() {
+		switch r := recover().(type) {
+		case exitCode:
+			if int(r) != code {
+				t.Errorf("Expected exit code %d, got %d", code, r)
+			}
+		case nil:
+			t.Errorf("Expected exit with the code %d", code)
+		default:
+			panic(r)
+		}
+	}()
+	f()
+}
+
+func TestExitCodes(t *testing.T) {
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	defer func(h string) { apiHost = h }(apiHost)
+
+	expectExit(t, exitUsage, func() { createAddress("") })
+
+	for status, code := range map[int]int{
+		http.StatusBadRequest:          exitAPI,
+		http.StatusUnauthorized:        exitAPI,
+		http.StatusInternalServerError: exitNetwork,
+		http.StatusServiceUnavailable:  exitNetwork,
+	} {
+		fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		apiHost = fakeServer.URL
+		expectExit(t, code, func() { sendV3("API-KEY", p) })
+		expectExit(t, code, func() { sendV2("USER", "PASSWORD", p) })
+		fakeServer.Close()
+	}
+
+	// The server is closed and not reachable anymore:
+	expectExit(t, exitNetwork, func() { sendV3("API-KEY", p) })
+	expectExit(t, exitNetwork, func() { sendV2("USER", "PASSWORD", p) })
+
+	// The command line errors are the usage errors:
+	defer RootCmd.SetOutput(nil)
+	RootCmd.SetOutput(ioutil.Discard)
+	RootCmd.SetArgs([]string{"--no-such-flag"})
+	expectExit(t, exitUsage, Execute)
+	expectExit(t, exitUsage, func() { flagInt(RootCmd, "subject") })
+}
+
+func TestResolveContentInlinePlainText(t *testing.T) {
+	f, err := ioutil.TempFile("", "body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("<table><tr><td>Complex</td></tr></table>")
+	f.Close()
+
+	for _, c := range []*contentSources{
+		{htmlFilename: f.Name(), plainText: "Inline text"},
+		{args: []string{"<p>Hello</p>"}, plainText: "Inline text"},
+		{templateID: "TEMPLATE-ID", plainText: "Inline text"},
+		{plainText: "Inline text"},
+	} {
+		_, plainTextContent := resolveContent(c)
+		if plainTextContent != "Inline text" {
+			t.Errorf("Expected the inline plain text for %+v, got %q", c, plainTextContent)
+		}
+	}
+
+	htmlContent, plainTextContent := resolveContent(&contentSources{args: []string{"<p>Hello</p>"}})
+	if htmlContent != "<p>Hello</p>" || plainTextContent != "Hello" {
+		t.Errorf("Expected the converted plain text, got %q and %q", htmlContent, plainTextContent)
+	}
+	expectExit(t, exitUsage, func() {
+		resolveContent(&contentSources{plainTextFilename: f.Name(), plainText: "Inline text"})
+	})
+}
+
+func TestPlainPrettyTables(t *testing.T) {
+	table := "<table><tr><th>Name</th><th>Qty</th></tr><tr><td>Apple</td><td>3</td></tr></table>"
+	f := tempFile(t, table)
+	defer os.Remove(f)
+	defer func() {
+		RootCmd.PersistentFlags().Set("plain-pretty-tables", "true")
+		prettyTables = true
+	}()
+
+	for _, pretty := range []bool{true, false} {
+		RootCmd.PersistentFlags().Set("plain-pretty-tables", strconv.FormatBool(pretty))
+		debugCmd(RootCmd)
+		_, plainTextContent := resolveContent(&contentSources{htmlFilename: f})
+		_, plainBody := messageBodies([]string{table})
+		for _, text := range []string{plainTextContent, plainBody} {
+			if strings.Contains(text, "+-") != pretty || !strings.Contains(text, "Apple") {
+				t.Errorf("Expected the table rendered with pretty tables %v, got:\n%s", pretty, text)
+			}
+		}
+	}
+}
+
+func TestRequireContent(t *testing.T) {
+	for _, c := range []struct{ templateID, htmlContent, plainTextContent string }{
+		{"TEMPLATE-ID", dummyContent, ""},
+		{"", "<p>Hello</p>", ""},
+		{"", "", "Hello"},
+	} {
+		if err := requireContent(c.templateID, c.htmlContent, c.plainTextContent); err != nil {
+			t.Errorf("Unexpected error for %+v: %v", c, err)
+		}
+	}
+	for _, c := range []struct{ htmlContent, plainTextContent string }{
+		{"", ""},
+		{dummyContent, ""},
+		{" \n", "\t"},
+	} {
+		if err := requireContent("", c.htmlContent, c.plainTextContent); err == nil {
+			t.Errorf("Expected an error for the empty content %+v", c)
+		}
+	}
+
+	f := tempFile(t, "\n")
+	defer os.Remove(f)
+	htmlContent, plainTextContent := resolveContent(&contentSources{htmlFilename: f})
+	if err := requireContent("", htmlContent, plainTextContent); err == nil {
+		t.Error("Expected an error for the empty HTML body file")
+	}
+}
+
+func TestV2FromNameEncoding(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fromName := r.FormValue("fromname"); fromName != "=?utf-8?q?J=C3=BCrgen_M=C3=BCller?=" {
+			t.Errorf("Expected RFC 2047 encoded from name, got %q", fromName)
+		}
+	}))
+	defer fakeServer.Close()
+
+	m := newV2Mail(&sendParams{
+		from:             "Jürgen Müller <juergen@example.com>",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	})
+	client := v2.NewSendGridClient("USER", "PASSWORD")
+	client.APIMail = fakeServer.URL
+	if err := client.Send(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := newV2Mail(&sendParams{from: "John Doe <john@example.com>"}); m.FromName != "John Doe" {
+		t.Errorf("ASCII names should be kept intact, got %q", m.FromName)
+	}
+}
+
+func TestInlineAttachment(t *testing.T) {
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader("Piped\ncontent")
+
+	var atts []*inlineAttachment
+	for _, spec := range []string{"notes.txt:text/plain:Hello: World", "piped.csv::@-"} {
+		a, err := parseInlineAttachment(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		atts = append(atts, a)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		inlineAtts:       atts,
+	})
+	if len(message.Attachments) != 2 {
+		t.Fatalf("Expected 2 attachments, got %d", len(message.Attachments))
+	}
+	for i, expected := range []struct{ filename, contentType, content string }{
+		{"notes.txt", "text/plain", "Hello: World"},
+		{"piped.csv", "text/csv; charset=utf-8", "Piped\ncontent"},
+	} {
+		a := message.Attachments[i]
+		content, _ := base64.StdEncoding.DecodeString(a.Content)
+		if a.Filename != expected.filename || a.Type != expected.contentType || string(content) != expected.content {
+			t.Errorf("Expected %+v, got %q %q %q", expected, a.Filename, a.Type, content)
+		}
+	}
+
+	if _, err := parseInlineAttachment("missing-content"); err == nil {
+		t.Error("parseInlineAttachment should fail on incorrect specification")
+	}
+}
+
+func TestAttachmentCharset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	csvFilename := filepath.Join(dir, "report.csv")
+	ioutil.WriteFile(csvFilename, []byte("name,total\nJürgen,1\n"), 0644)
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		attFilenames:     []string{csvFilename},
+		inlineAtts: []*inlineAttachment{
+			{name: "notes.txt", contentType: "text/plain", content: []byte("Notes")},
+			{name: "logo.png", contentType: "image/png", content: []byte("PNG")},
+		},
+	}
+	if types := attachmentTypes(newV3Message(p)); types[0] != "text/csv; charset=utf-8" || types[1] != "text/plain" {
+		t.Errorf("Expected the types intact without the charset, got %q", types)
+	}
+	p.attCharset = "iso-8859-1"
+	expected := []string{"text/csv; charset=iso-8859-1", "text/plain; charset=iso-8859-1", "image/png"}
+	if types := attachmentTypes(newV3Message(p)); !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected the types %q, got %q", expected, types)
+	}
+}
+
+func attachmentTypes(message *mail.SGMailV3) []string {
+	types := make([]string, len(message.Attachments))
+	for i, a := range message.Attachments {
+		types[i] = a.Type
+	}
+	return types
+}
+
+func TestVCardAttachment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	card := "BEGIN:VCARD\nVERSION:3.0\nFN:John Doe\nEND:VCARD\n"
+	filename := filepath.Join(dir, "john.card")
+	if err := ioutil.WriteFile(filename, []byte(card), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := vcardAttachment(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		inlineAtts:       []*inlineAttachment{a},
+	})
+	att := message.Attachments[0]
+	content, _ := base64.StdEncoding.DecodeString(att.Content)
+	if att.Type != "text/vcard" || att.Filename != "john.vcf" || string(content) != card {
+		t.Errorf("Expected the text/vcard attachment john.vcf, got %q %q %q", att.Filename, att.Type, content)
+	}
+	if _, err := vcardAttachment(filepath.Join(dir, "missing.vcf")); err == nil {
+		t.Error("Expected an error for the missing file")
+	}
+}
+
+func TestCopySelf(t *testing.T) {
+	from := "me@example.com"
+	ccs, bccs, _ := copySelf(from, []string{"cc@example.com"}, []string{"bcc@example.com"}, true, false)
+	if len(ccs) != 2 || ccs[1] != from || len(bccs) != 1 {
+		t.Errorf("Expected the from address in CC only, got CC: %v, BCC: %v", ccs, bccs)
+	}
+	ccs, bccs, _ = copySelf(from, []string{"cc@example.com"}, nil, false, true)
+	if len(ccs) != 1 || len(bccs) != 1 || bccs[0] != from {
+		t.Errorf("Expected the from address in BCC only, got CC: %v, BCC: %v", ccs, bccs)
+	}
+	if _, _, err := copySelf(from, nil, nil, true, true); err == nil {
+		t.Error("--cc-self and --bcc-self should be mutually exclusive")
+	}
+}
+
+func TestOnBehalfOf(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subuser := r.Header.Get("on-behalf-of"); subuser != "subuser1" {
+			t.Errorf("Expected on-behalf-of header 'subuser1', got %q", subuser)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h, s string) { apiHost, onBehalfOf = h, s }(apiHost, onBehalfOf)
+	apiHost, onBehalfOf = fakeServer.URL, "subuser1"
+
+	sendV3("API-KEY", &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	})
+}
+
+func TestRootCmdAcceptsContentArgs(t *testing.T) {
+	cmd, args, err := RootCmd.Find([]string{"<strong>Hello</strong>", "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd != RootCmd || len(args) != 2 {
+		t.Errorf("Expected the root command with the content arguments, got %q %v", cmd.Name(), args)
+	}
+	if err := cmd.ValidateArgs(args); err != nil {
+		t.Errorf("The content arguments should be accepted: %v", err)
+	}
+}
+
+// Captures the log output of f.
+func captureLog(f func()) string {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	f()
+	return buf.String()
+}
+
+func TestRequestBodyWithData(t *testing.T) {
+	body, err := requestBody(&sendParams{
+		from:         "sender@example.com",
+		tos:          []string{"to@example.com"},
+		subject:      "Test",
+		htmlContent:  dummyContent,
+		templateID:   "TEMPLATE-ID",
+		templateData: map[string]interface{}{"name": "John"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		TemplateID       string `json:"template_id"`
+		Personalizations []struct {
+			To   []map[string]string    `json:"to"`
+			Data map[string]interface{} `json:"dynamic_template_data"`
+		} `json:"personalizations"`
+	}
+	json.Unmarshal(body, &decoded)
+	if decoded.TemplateID != "TEMPLATE-ID" || len(decoded.Personalizations) != 1 ||
+		decoded.Personalizations[0].To[0]["email"] != "to@example.com" ||
+		decoded.Personalizations[0].Data["name"] != "John" {
+		t.Errorf("Unexpected request body: %s", body)
+	}
+}
+
+func TestRequestBodyWithReplyToList(t *testing.T) {
+	filename := tempFile(t, "support@example.com\nSales <sales@example.com>\n")
+	defer os.Remove(filename)
+	addresses, err := readAddresses(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replyTo, replyToList, err := mergeReplyTos("", addresses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := requestBody(&sendParams{
+		from:        "sender@example.com",
+		tos:         []string{"to@example.com"},
+		subject:     "Test",
+		htmlContent: dummyContent,
+		replyTo:     replyTo,
+		replyToList: replyToList,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		ReplyTo     map[string]string   `json:"reply_to"`
+		ReplyToList []map[string]string `json:"reply_to_list"`
+	}
+	json.Unmarshal(body, &decoded)
+	if decoded.ReplyTo != nil || len(decoded.ReplyToList) != 2 ||
+		decoded.ReplyToList[0]["email"] != "support@example.com" ||
+		decoded.ReplyToList[1]["email"] != "sales@example.com" || decoded.ReplyToList[1]["name"] != "Sales" {
+		t.Errorf("Expected both reply-to addresses in the reply-to list: %s", body)
+	}
+}
+
+func TestPrefixSubject(t *testing.T) {
+	for _, c := range []struct{ prefix, subject, expected string }{
+		{"", "Hello", "Hello"},
+		{"[STAGING] ", "Hello", "[STAGING] Hello"},
+		{"[STAGING] ", "[STAGING] Hello", "[STAGING] Hello"},
+	} {
+		subject := prefixSubject(c.prefix, c.subject)
+		if subject != c.expected {
+			t.Errorf("Expected subject %q, got %q", c.expected, subject)
+		}
+		if subject = prefixSubject(c.prefix, subject); subject != c.expected {
+			t.Errorf("The prefix should be added only once, got %q", subject)
+		}
+	}
+}
+
+func TestCheckSubjectLength(t *testing.T) {
+	long := strings.Repeat("Subject ", 130)
+	output := captureLog(func() {
+		if err := checkSubjectLength(long, 998, false); err != nil {
+			t.Error(err)
+		}
+	})
+	if !strings.Contains(output, "exceeding the limit of 998") {
+		t.Errorf("Expected a warning about the overlong subject, got %q", output)
+	}
+	if err := checkSubjectLength(long, 998, true); err == nil {
+		t.Error("Expected an error in the strict mode")
+	}
+	output = captureLog(func() { checkSubjectLength(strings.Repeat("x", 100), 998, true) })
+	if !strings.Contains(output, "may get truncated") {
+		t.Errorf("Expected a notice about the subject longer than %d, got %q", recommendedSubjectLength, output)
+	}
+	output = captureLog(func() { checkSubjectLength(long, 0, true) })
+	if strings.Contains(output, "exceeding") {
+		t.Errorf("The length should be unlimited, got %q", output)
+	}
+	if output = captureLog(func() { checkSubjectLength("Hello", 998, true) }); output != "" {
+		t.Errorf("Expected no warnings, got %q", output)
+	}
+}
+
+func TestCheckCredentials(t *testing.T) {
+	output := captureLog(func() {
+		if err := checkCredentials("API-KEY", "USER", "PASSWORD", false); err != nil {
+			t.Error(err)
+		}
+	})
+	if !strings.Contains(output, "using the API key") {
+		t.Errorf("Expected a warning about both credential sets, got %q", output)
+	}
+	if err := checkCredentials("API-KEY", "USER", "PASSWORD", true); err == nil {
+		t.Error("Expected an error in the strict mode")
+	}
+	output = captureLog(func() { checkCredentials("API-KEY", "", "", true) })
+	if output != "" {
+		t.Errorf("Expected no warnings with API key only, got %q", output)
+	}
+}
+
+func TestCreateAddressGroup(t *testing.T) {
+	output := captureLog(func() {
+		expectExit(t, exitUsage, func() { createAddress("Team: a@example.com, b@example.com;") })
+	})
+	if !strings.Contains(output, "multiple --to flags") {
+		t.Errorf("Expected the group address rejection to point to --to, got %q", output)
+	}
+	if a := createAddress("Team <team@example.com>"); a.Address != "team@example.com" {
+		t.Errorf("Unexpected address %q", a.Address)
+	}
+}
+
+func TestFromNameFallback(t *testing.T) {
+	from := withFallbackName("sender@example.com", "Example Team")
+	if from != "Example Team <sender@example.com>" {
+		t.Errorf("Expected the fallback name, got %q", from)
+	}
+	if a := senderAddress(&sendParams{from: from}); a.Name != "Example Team" || a.Address != "sender@example.com" {
+		t.Errorf("Unexpected sender %+v", a)
+	}
+	if from := withFallbackName("John Doe <john@example.com>", "Example Team"); from != "John Doe <john@example.com>" {
+		t.Errorf("The given name should not be overridden, got %q", from)
+	}
+	if from := withFallbackName("sender@example.com", ""); from != "sender@example.com" {
+		t.Errorf("Expected the address intact without the fallback, got %q", from)
+	}
+}
+
+func TestCheckFrom(t *testing.T) {
+	for _, from := range []string{"sender@example.com", "John Doe <john@example.com>"} {
+		if err := checkFrom(from); err != nil {
+			t.Errorf("Unexpected error for %q: %v", from, err)
+		}
+	}
+	err := checkFrom("John Doe")
+	if err == nil || !strings.Contains(err.Error(), `"John Doe <sender@example.com>"`) {
+		t.Errorf("Expected the error suggesting the \"Name <address>\" form, got %v", err)
+	}
+
+	defer func() {
+		fromFlag.reset(placeholderFrom)
+		RootCmd.PersistentFlags().Lookup("from").Changed = false
+	}()
+	RootCmd.SetArgs([]string{"-f", "John Doe"})
+	output := captureLog(func() {
+		expectExit(t, exitUsage, func() { RootCmd.Execute() })
+	})
+	if !strings.Contains(output, "is not an email address") {
+		t.Errorf("Expected the FROM address rejection, got %q", output)
+	}
+}
+
+func TestNoTracking(t *testing.T) {
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+		noTracking:       true,
+	})
+	ts := message.TrackingSettings
+	if ts == nil || ts.OpenTracking == nil || ts.ClickTracking == nil {
+		t.Fatalf("Expected the tracking settings, got %+v", ts)
+	}
+	if ts.OpenTracking.Enable == nil || *ts.OpenTracking.Enable {
+		t.Error("Expected the open tracking to be disabled")
+	}
+	if ts.ClickTracking.Enable == nil || *ts.ClickTracking.Enable {
+		t.Error("Expected the click tracking to be disabled")
+	}
+}
+
+func TestClassifyContentFile(t *testing.T) {
+	for _, c := range []struct{ filename, html, plain string }{
+		{"message.html", "message.html", ""},
+		{"message.HTM", "message.HTM", ""},
+		{"message.txt", "", "message.txt"},
+	} {
+		html, plain, err := classifyContentFile(c.filename)
+		if err != nil || html != c.html || plain != c.plain {
+			t.Errorf("Unexpected classification of %q: %q, %q (%v)", c.filename, html, plain, err)
+		}
+	}
+	if _, _, err := classifyContentFile("message.pdf"); err == nil {
+		t.Error("Expected an error for the unknown extension")
+	}
+
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	htmlFilename, textFilename := filepath.Join(dir, "body.html"), filepath.Join(dir, "body.txt")
+	ioutil.WriteFile(htmlFilename, []byte("<p>Hello</p>"), 0644)
+	ioutil.WriteFile(textFilename, []byte("Plain hello"), 0644)
+
+	html, plain, _ := classifyContentFile(htmlFilename)
+	htmlContent, plainTextContent := resolveContent(&contentSources{htmlFilename: html, plainTextFilename: plain})
+	if htmlContent != "<p>Hello</p>" || plainTextContent != "Hello" {
+		t.Errorf("Unexpected content of the HTML file: %q, %q", htmlContent, plainTextContent)
+	}
+	html, plain, _ = classifyContentFile(textFilename)
+	htmlContent, plainTextContent = resolveContent(&contentSources{htmlFilename: html, plainTextFilename: plain})
+	if htmlContent != "" || plainTextContent != "Plain hello" {
+		t.Errorf("Unexpected content of the plain-text file: %q, %q", htmlContent, plainTextContent)
+	}
+}
+
+// Creates a synthetic attachment file of the given size.
+func largeFile(tb testing.TB, size int) string {
+	f, err := ioutil.TempFile("", "sendgrid-cli")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	if _, err := f.Write(b); err != nil {
+		tb.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestEncodeFile(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 3, 32*1024 + 1, 5*1024*1024 + 2} {
+		filename := largeFile(t, size)
+		defer os.Remove(filename)
+		content, err := encodeFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadFile(filename)
+		if content != base64.StdEncoding.EncodeToString(b) {
+			t.Errorf("The streamed encoding of %d bytes differs from the all-at-once result", size)
+		}
+	}
+}
+
+func BenchmarkEncodeFileAllAtOnce(b *testing.B) {
+	filename := largeFile(b, 20*1024*1024)
+	defer os.Remove(filename)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		content, _ := ioutil.ReadFile(filename)
+		base64.StdEncoding.EncodeToString(content)
+	}
+}
+
+func BenchmarkEncodeFile(b *testing.B) {
+	filename := largeFile(b, 20*1024*1024)
+	defer os.Remove(filename)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encodeFile(filename)
+	}
+}
+
+func TestRawFrom(t *testing.T) {
+	p := &sendParams{
+		from:             "Sender@Example.com",
+		rawFrom:          mail.NewEmail(`Doe, John "JD" <Sales>`, "Sender@Example.com"),
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	message := newV3Message(p)
+	if message.From.Name != `Doe, John "JD" <Sales>` || message.From.Address != "Sender@Example.com" {
+		t.Errorf("Expected the raw sender to be used verbatim, got %q <%s>", message.From.Name, message.From.Address)
+	}
+	m := newV2Mail(p)
+	if m.From != "Sender@Example.com" {
+		t.Errorf("Expected the raw address to be used verbatim, got %q", m.From)
+	}
+}
+
+func TestSendSummary(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Message-Id", "MSG-ID-42")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, w io.Writer, q bool) { apiHost, stdout, quiet = h, w, q }(apiHost, stdout, quiet)
+	var out bytes.Buffer
+	apiHost, stdout = fakeServer.URL, &out
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"a@example.com", "b@example.com"},
+		ccs:              []string{"c@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	if err := deliverV3("API-KEY", p); err != nil {
+		t.Fatal(err)
+	}
+	if summary := out.String(); !strings.Contains(summary, "MSG-ID-42") ||
+		!strings.Contains(summary, "3 recipient(s)") || !strings.Contains(summary, `"Test"`) {
+		t.Errorf("Unexpected summary: %q", summary)
+	}
+
+	out.Reset()
+	quiet = true
+	if err := deliverV3("API-KEY", p); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no summary in the quiet mode, got %q", out.String())
+	}
+}
+
+func TestDefaultFrom(t *testing.T) {
+	defer func(f func() string, env string) {
+		gitUserEmail = f
+		os.Setenv("SENDGRID_FROM", env)
+	}(gitUserEmail, os.Getenv("SENDGRID_FROM"))
+	os.Unsetenv("SENDGRID_FROM")
+
+	gitUserEmail = func() string { return "dev@example.com" }
+	var from string
+	output := captureLog(func() { from = defaultFrom() })
+	if from != "dev@example.com" || !strings.Contains(output, "git config") {
+		t.Errorf("Expected the git user email, got %q (%q)", from, output)
+	}
+
+	os.Setenv("SENDGRID_FROM", "env@example.com")
+	if from = defaultFrom(); from != "env@example.com" {
+		t.Errorf("Expected SENDGRID_FROM to take precedence, got %q", from)
+	}
+
+	os.Unsetenv("SENDGRID_FROM")
+	gitUserEmail = func() string { return "" }
+	if from = defaultFrom(); from != placeholderFrom {
+		t.Errorf("Expected the placeholder, got %q", from)
+	}
+}
+
+func TestReadGzippedContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sendgrid-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "body.html.gz")
+	f, _ := os.Create(filename)
+	w := gzip.NewWriter(f)
+	w.Write([]byte("<p>Compressed hello</p>"))
+	w.Close()
+	f.Close()
+
+	html, plain, err := classifyContentFile(filename)
+	if err != nil || html != filename || plain != "" {
+		t.Fatalf("Expected the gzipped HTML file, got %q, %q (%v)", html, plain, err)
+	}
+	htmlContent, plainTextContent := resolveContent(&contentSources{htmlFilename: html})
+	if htmlContent != "<p>Compressed hello</p>" || plainTextContent != "Compressed hello" {
+		t.Errorf("Unexpected content of the gzipped file: %q, %q", htmlContent, plainTextContent)
+	}
+
+	forced := filepath.Join(dir, "body.html")
+	os.Rename(filename, forced)
+	htmlContent, _ = resolveContent(&contentSources{htmlFilename: forced, gzip: true})
+	if htmlContent != "<p>Compressed hello</p>" {
+		t.Errorf("Expected the content to be decompressed with --gzip, got %q", htmlContent)
+	}
+}
+
+func TestTrimContent(t *testing.T) {
+	filename := tempFile(t, "\n\n  Hello,\n\n  World!  \n\t\n")
+	defer os.Remove(filename)
+
+	_, plainTextContent := resolveContent(&contentSources{plainTextFilename: filename})
+	if plainTextContent != "\n\n  Hello,\n\n  World!  \n\t\n" {
+		t.Errorf("Expected the exact content without --trim, got %q", plainTextContent)
+	}
+	_, plainTextContent = resolveContent(&contentSources{plainTextFilename: filename, trim: true})
+	if plainTextContent != "Hello,\n\n  World!" {
+		t.Errorf("Expected the trimmed content with --trim, got %q", plainTextContent)
+	}
+}
+
+func TestValidateContent(t *testing.T) {
+	if err := validateContent("HTML", "<p>Привет</p>", 100); err != nil {
+		t.Error(err)
+	}
+	err := validateContent("plain-text", "Hello \xff\xfe", 0)
+	if err == nil || !strings.Contains(err.Error(), "plain-text") || !strings.Contains(err.Error(), "UTF-8") {
+		t.Errorf("Expected the invalid UTF-8 to be rejected naming the part, got %v", err)
+	}
+	if err := validateContent("HTML", strings.Repeat("x", 11), 10); err == nil || !strings.Contains(err.Error(), "HTML") {
+		t.Errorf("Expected the oversized content to be rejected, got %v", err)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var agents []string
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			w.Write([]byte(`{"message": "success"}`))
+		} else {
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer fakeServer.Close()
+	defer func(h, ua string, w io.Writer) { apiHost, userAgent, stdout = h, ua, w }(apiHost, userAgent, stdout)
+	apiHost, stdout = fakeServer.URL, &bytes.Buffer{}
+
+	p := &sendParams{
+		from:             "sender@example.com",
+		tos:              []string{"to@example.com"},
+		subject:          "Test",
+		plainTextContent: "Test",
+	}
+	if err := deliverV3("API-KEY", p); err != nil {
+		t.Fatal(err)
+	}
+	if err := deliverV2("USER", "PASSWORD", p); err != nil {
+		t.Fatal(err)
+	}
+	userAgent = "monitoring/1.0"
+	deliverV3("API-KEY", p)
+	deliverV2("USER", "PASSWORD", p)
+
+	expected := []string{"sendgrid-cli/" + Version, "sendgrid-cli/" + Version, "monitoring/1.0", "monitoring/1.0"}
+	if strings.Join(agents, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected User-Agent headers %v, got %v", expected, agents)
+	}
+}
+
+func TestSelectAPIVersion(t *testing.T) {
+	if apiKey, err := selectAPIVersion("2", "API-KEY", "USER", "PASSWORD"); err != nil || apiKey != "" {
+		t.Errorf("Expected v2 without the API key, got %q (%v)", apiKey, err)
+	}
+	if _, err := selectAPIVersion("2", "API-KEY", "", ""); err == nil {
+		t.Error("Expected an error for v2 without the username and password")
+	}
+	if apiKey, err := selectAPIVersion("3", "API-KEY", "USER", "PASSWORD"); err != nil || apiKey != "API-KEY" {
+		t.Errorf("Expected v3 with the API key, got %q (%v)", apiKey, err)
+	}
+	defer os.Setenv("SENDGRID_API_KEY", os.Getenv("SENDGRID_API_KEY"))
+	os.Unsetenv("SENDGRID_API_KEY")
+	if _, err := selectAPIVersion("3", "", "USER", "PASSWORD"); err == nil {
+		t.Error("Expected an error for v3 without the API key")
+	}
+	if _, err := selectAPIVersion("4", "API-KEY", "", ""); err == nil {
+		t.Error("Expected an error for the unsupported version")
+	}
+}
+
+func TestForceAPIVersion2(t *testing.T) {
+	called := false
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if r.URL.Path != "/api/mail.send.json" {
+			t.Errorf("Expected the v2 API call, got %q", r.URL.Path)
+		}
+		if user := r.FormValue("api_user"); user != "USER" {
+			t.Errorf("Expected the v2 credentials, got the user %q", user)
+		}
+		w.Write([]byte(`{"message": "success"}`))
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	toFile := tempFile(t, "to@example.com\n")
+	defer os.Remove(toFile)
+	defer func() {
+		for name, value := range map[string]string{
+			"key": "", "user": "", "password": "", "api-version": "", "to-file": "", "subject": "",
+			"plain-text": "", "quiet": "false"} {
+			RootCmd.PersistentFlags().Set(name, value)
+			RootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+		fromFlag.reset(placeholderFrom)
+		RootCmd.PersistentFlags().Lookup("from").Changed = false
+	}()
+	RootCmd.SetArgs([]string{"-k", "API-KEY", "-U", "USER", "-P", "PASSWORD", "--api-version", "2",
+		"-f", "sender@example.com", "--to-file", toFile, "-s", "Test", "--plain-text", "Test", "-q"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("Expected the message to be sent with v2 API")
+	}
+}
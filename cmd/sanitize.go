@@ -0,0 +1,34 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "github.com/microcosm-cc/bluemonday"
+
+// Email-safe HTML policy based on bluemonday's user generated content policy.
+// It removes <script>, <style>, <iframe>, <object>, <embed>, <form>, <input>
+// and the other interactive elements, all on* event handler attributes, inline
+// "style" attributes and "javascript:" URLs. The legacy table layout attributes
+// widely used in emails are kept.
+var sanitizePolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("align", "valign", "width", "height", "bgcolor", "border",
+		"cellpadding", "cellspacing").Globally()
+	return p
+}()
+
+// Strips dangerous tags and attributes from the HTML body.
+func sanitizeHTML(html string) string {
+	return sanitizePolicy.Sanitize(html)
+}
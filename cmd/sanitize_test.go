@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	html := `<p onclick="steal()">Hello</p><script>alert("XSS")</script>` +
+		`<table width="100%"><tr><td align="center">Cell</td></tr></table>`
+	sanitized := sanitizeHTML(html)
+	if strings.Contains(sanitized, "<script") || strings.Contains(sanitized, "alert") {
+		t.Errorf("The script tag should be stripped: %s", sanitized)
+	}
+	if strings.Contains(sanitized, "onclick") {
+		t.Errorf("The event handler should be stripped: %s", sanitized)
+	}
+	if !strings.Contains(sanitized, "<p>Hello</p>") || !strings.Contains(sanitized, `align="center"`) {
+		t.Errorf("The safe content should be kept: %s", sanitized)
+	}
+}
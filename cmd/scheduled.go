@@ -0,0 +1,101 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// scheduledCmd represents the scheduled command
+var scheduledCmd = &cobra.Command{
+	Use:   "scheduled",
+	Short: "Manage scheduled sends",
+}
+
+// scheduledListCmd represents the scheduled list command
+var scheduledListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the scheduled sends",
+	Long: `Lists the batches of the scheduled sends that were paused or cancelled
+with their statuses, eg,
+
+sendgrid-cli scheduled list -k API-KEY
+`,
+	Run: scheduledList,
+}
+
+func init() {
+	RootCmd.AddCommand(scheduledCmd)
+	scheduledCmd.AddCommand(scheduledListCmd)
+}
+
+// Scheduled send batch
+type scheduledSend struct {
+	BatchID string `json:"batch_id"`
+	Status  string `json:"status"`
+}
+
+func scheduledList(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	sends, err := fetchScheduledSends(apiKeyFlag(cmd))
+	if err != nil {
+		log.Error("Failed to retrieve the scheduled sends.")
+		fail(errorExitCode(err), err)
+	}
+
+	if len(sends) == 0 && outputFormat(cmd) == outputTable {
+		log.Info("There are no scheduled sends.")
+		return
+	}
+	rows := make([][]string, len(sends))
+	for i, s := range sends {
+		rows[i] = []string{s.BatchID, s.Status}
+	}
+	err = printOutput(cmd, sends, []string{"BATCH ID", "STATUS"}, rows)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+}
+
+// Retrieves the scheduled sends.
+func fetchScheduledSends(apiKey string) ([]scheduledSend, error) {
+	sends := []scheduledSend{}
+	if err := apiGet(apiKey, "/v3/user/scheduled_sends", nil, &sends); err != nil {
+		return nil, err
+	}
+	if sends == nil {
+		sends = []scheduledSend{} // the API responds with null if there are none
+	}
+	return sends, nil
+}
+
+// Creates a new batch ID used to group the scheduled sends.
+func createBatch(apiKey string) (string, error) {
+	response, err := apiRequest(apiKey, rest.Post, "/v3/mail/batch", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	var batch scheduledSend
+	if err := json.Unmarshal([]byte(response.Body), &batch); err != nil {
+		return "", err
+	}
+	return batch.BatchID, nil
+}
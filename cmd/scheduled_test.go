@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchScheduledSends(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/user/scheduled_sends" {
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+		fmt.Fprintln(w, `[{"batch_id": "BATCH-1", "status": "pause"}, {"batch_id": "BATCH-2", "status": "cancel"}]`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	sends, err := fetchScheduledSends("API-KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sends) != 2 || sends[0].BatchID != "BATCH-1" || sends[1].Status != "cancel" {
+		t.Errorf("Unexpected scheduled sends: %+v", sends)
+	}
+}
+
+func TestFetchScheduledSendsEmpty(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `null`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	sends, err := fetchScheduledSends("API-KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printJSON(&out, sends)
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("Expected an empty JSON list, got %q", out.String())
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/mail/batch" {
+			t.Errorf("Unexpected request %s %q", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintln(w, `{"batch_id": "BATCH-1"}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	batchID, err := createBatch("API-KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batchID != "BATCH-1" {
+		t.Errorf("Expected the batch ID BATCH-1, got %q", batchID)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// sendRawCmd represents the send-raw command
+var sendRawCmd = &cobra.Command{
+	Use:   "send-raw",
+	Short: "Send a complete SendGrid v3 mail/send JSON body",
+	Long: `Reads a complete SendGrid v3 /v3/mail/send JSON body from the standard input
+or from the file given with --file and sends it verbatim, eg,
+
+cat message.json | sendgrid-cli send-raw -k API-KEY
+sendgrid-cli send-raw -k API-KEY --file message.json
+
+This is an escape hatch for the fields not supported by the CLI options.
+`,
+	Run: sendRaw,
+}
+
+func init() {
+	RootCmd.AddCommand(sendRawCmd)
+	sendRawCmd.Flags().String("file", "", "File with the JSON body (default is the standard input).")
+}
+
+func sendRaw(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	var body []byte
+	var err error
+	if filename := flagString(cmd, "file"); filename != "" {
+		body, err = ioutil.ReadFile(filename)
+	} else {
+		body, err = ioutil.ReadAll(stdin)
+	}
+	if err != nil {
+		log.Error("Failed to read the JSON body.")
+		fail(exitUsage, err)
+	}
+	if !json.Valid(body) {
+		fail(exitUsage, "The message body is not valid JSON.")
+	}
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	if _, err := postRaw(apiKeyFlag(cmd), body); err != nil {
+		log.Error("Failed to send the message.")
+		fail(errorExitCode(err), err)
+	}
+}
+
+// POSTs the JSON body as is to the v3 mail/send endpoint.
+func postRaw(apiKey string, body []byte) (*rest.Response, error) {
+	return apiRequest(apiKey, rest.Post, "/v3/mail/send", nil, body)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostRaw(t *testing.T) {
+	raw := []byte(`{"personalizations":[{"to":[{"email":"a@example.com"}]}],` +
+		`"from":{"email":"b@example.com"},"subject":"Raw","unsupported_field":{"x":1},` +
+		`"content":[{"type":"text/plain","value":"Hi"}]}`)
+
+	var received []byte
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/mail/send" || r.Method != "POST" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer API-KEY" {
+			t.Errorf("Missing authorization header: %q", r.Header.Get("Authorization"))
+		}
+		received, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	response, err := postRaw("API-KEY", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status code 202, got %d", response.StatusCode)
+	}
+	if !bytes.Equal(received, raw) {
+		t.Errorf("The raw body was altered:\n%s\n%s", raw, received)
+	}
+}
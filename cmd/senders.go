@@ -0,0 +1,107 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// sendersCmd represents the senders command
+var sendersCmd = &cobra.Command{
+	Use:   "senders",
+	Short: "Manage verified single senders",
+}
+
+// sendersListCmd represents the senders list command
+var sendersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List verified single senders",
+	Long: `Lists the single sender identities of the account with their FROM addresses
+and whether they are verified, eg,
+
+sendgrid-cli senders list -k API-KEY
+`,
+	Run: sendersList,
+}
+
+func init() {
+	RootCmd.AddCommand(sendersCmd)
+	sendersCmd.AddCommand(sendersListCmd)
+}
+
+// Single sender identity
+type verifiedSender struct {
+	ID        int    `json:"id"`
+	Nickname  string `json:"nickname"`
+	FromEmail string `json:"from_email"`
+	FromName  string `json:"from_name"`
+	ReplyTo   string `json:"reply_to"`
+	Verified  bool   `json:"verified"`
+}
+
+func sendersList(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	senders, err := fetchVerifiedSenders(apiKeyFlag(cmd))
+	if err != nil {
+		log.Error("Failed to retrieve the verified senders.")
+		fail(errorExitCode(err), err)
+	}
+
+	rows := make([][]string, len(senders))
+	for i, s := range senders {
+		rows[i] = []string{strconv.Itoa(s.ID), s.Nickname, s.FromEmail, s.FromName, strconv.FormatBool(s.Verified)}
+	}
+	err = printOutput(cmd, senders, []string{"ID", "NICKNAME", "FROM EMAIL", "FROM NAME", "VERIFIED"}, rows)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+}
+
+// Retrieves the single sender identities.
+func fetchVerifiedSenders(apiKey string) ([]verifiedSender, error) {
+	var result struct {
+		Results []verifiedSender `json:"results"`
+	}
+	if err := apiGet(apiKey, "/v3/verified_senders", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// Returns the warning if the FROM address isn't a verified single sender.
+func senderWarnings(apiKey, from string) ([]string, error) {
+	senders, err := fetchVerifiedSenders(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range senders {
+		if strings.EqualFold(s.FromEmail, from) {
+			if s.Verified {
+				return nil, nil
+			}
+			return []string{fmt.Sprintf("The sender %q is pending verification, SendGrid will reject the message", from)}, nil
+		}
+	}
+	return []string{fmt.Sprintf("The sender %q is not a verified sender, SendGrid will reject the message "+
+		"unless its domain is authenticated", from)}, nil
+}
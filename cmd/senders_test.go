@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func verifiedSendersServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/verified_senders" {
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+		fmt.Fprintln(w, `{"results": [
+			{"id": 1, "nickname": "Support", "from_email": "support@example.com", "from_name": "Support", "verified": true},
+			{"id": 2, "nickname": "Sales", "from_email": "sales@example.com", "from_name": "Sales", "verified": false}]}`)
+	}))
+}
+
+func TestSendersList(t *testing.T) {
+	fakeServer := verifiedSendersServer(t)
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	var out bytes.Buffer
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = &out
+	RootCmd.SetArgs([]string{"senders", "list", "-k", "API-KEY", "--output", "csv"})
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "ID,NICKNAME,FROM EMAIL,FROM NAME,VERIFIED\n" +
+		"1,Support,support@example.com,Support,true\n2,Sales,sales@example.com,Sales,false\n"
+	if out.String() != expected {
+		t.Errorf("Expected CSV output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestSenderWarnings(t *testing.T) {
+	fakeServer := verifiedSendersServer(t)
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	for from, expected := range map[string]string{
+		"Support@example.com": "",
+		"sales@example.com":   "pending verification",
+		"other@example.com":   "not a verified sender",
+	} {
+		warnings, err := senderWarnings("API-KEY", from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected == "" && len(warnings) != 0 ||
+			expected != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], expected)) {
+			t.Errorf("Expected the warning %q for %q, got %q", expected, from, warnings)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"github.com/spf13/cobra"
+)
+
+// Message spec read with --from-file. The "spec" tag lists the validation
+// rules of the field: "required" and "address" (the values should be email
+// addresses).
+type messageSpec struct {
+	From        string                 `json:"from" spec:"address"`
+	To          []string               `json:"to" spec:"required,address"`
+	Cc          []string               `json:"cc" spec:"address"`
+	Bcc         []string               `json:"bcc" spec:"address"`
+	ReplyTo     string                 `json:"reply_to" spec:"address"`
+	Subject     string                 `json:"subject"`
+	HTML        string                 `json:"html"`  // HTML body file name relative to the spec
+	Plain       string                 `json:"plain"` // plain-text body file name relative to the spec
+	PlainText   string                 `json:"plain_text"`
+	TemplateID  string                 `json:"template_id"`
+	Data        map[string]interface{} `json:"data"`
+	Headers     map[string]string      `json:"headers"`
+	SendAt      sendTime               `json:"send_at"`
+	Attachments []string               `json:"attachments"` // file names relative to the spec
+	Tracking    *specTracking          `json:"tracking_settings"`
+}
+
+// Tracking settings of the message spec
+type specTracking struct {
+	ClickTracking        *mail.ClickTrackingSetting        `json:"click_tracking"`
+	OpenTracking         *mail.OpenTrackingSetting         `json:"open_tracking"`
+	SubscriptionTracking *mail.SubscriptionTrackingSetting `json:"subscription_tracking"`
+	GoogleAnalytics      *mail.GaSetting                   `json:"ganalytics"`
+}
+
+// Rejects the unknown settings, eg, the mail settings given as tracking ones.
+func (t *specTracking) UnmarshalJSON(b []byte) error {
+	type settings specTracking // without the UnmarshalJSON method
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*settings)(t))
+}
+
+// Returns the tracking settings of the message.
+func (t *specTracking) settings() *mail.TrackingSettings {
+	if t == nil {
+		return nil
+	}
+	return &mail.TrackingSettings{
+		ClickTracking:        t.ClickTracking,
+		OpenTracking:         t.OpenTracking,
+		SubscriptionTracking: t.SubscriptionTracking,
+		GoogleAnalytics:      t.GoogleAnalytics,
+	}
+}
+
+// UNIX timestamp or RFC 3339 time given either as a number or a string
+type sendTime string
+
+func (t *sendTime) UnmarshalJSON(b []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err == nil {
+		*t = sendTime(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*t = sendTime(s)
+	return nil
+}
+
+// Validation errors of the message spec, one per field
+type specErrors []string
+
+func (e specErrors) Error() string {
+	return "invalid message spec:\n  " + strings.Join(e, "\n  ")
+}
+
+// Reads and validates the message spec JSON file. The relative file names
+// in the spec are resolved against the spec's directory.
+func readMessageSpec(filename string) (*messageSpec, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := parseMessageSpec(b)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(filename)
+	resolve := func(name string) string {
+		if name == "" || filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, name)
+	}
+	spec.HTML, spec.Plain = resolve(spec.HTML), resolve(spec.Plain)
+	for i, a := range spec.Attachments {
+		spec.Attachments[i] = resolve(a)
+	}
+	return spec, nil
+}
+
+// Parses the message spec reporting all the unknown keys, mistyped
+// and missing fields, incorrect addresses and the send time.
+func parseMessageSpec(b []byte) (*messageSpec, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("the message spec should be a JSON object: %v", err)
+	}
+	var spec messageSpec
+	var errs specErrors
+	known := make(map[string]bool)
+	v := reflect.ValueOf(&spec).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := field.Tag.Get("json")
+		known[key] = true
+		rules := strings.Split(field.Tag.Get("spec"), ",")
+		value, ok := raw[key]
+		if !ok {
+			if hasRule(rules, "required") {
+				errs = append(errs, key+": required")
+			}
+			continue
+		}
+		if err := json.Unmarshal(value, v.Field(i).Addr().Interface()); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: expected %s", key, specType(field.Type)))
+			continue
+		}
+		if hasRule(rules, "required") && v.Field(i).Len() == 0 {
+			errs = append(errs, key+": required")
+		}
+		if hasRule(rules, "address") {
+			errs = append(errs, specAddressErrors(key, v.Field(i))...)
+		}
+	}
+	var unknown []string
+	for key := range raw {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, key+": unknown key")
+	}
+	if spec.SendAt != "" {
+		if _, err := parseSendAt(string(spec.SendAt)); err != nil {
+			errs = append(errs, "send_at: "+err.Error())
+		}
+	}
+	if spec.Subject == "" && spec.TemplateID == "" {
+		errs = append(errs, "subject: required unless template_id is given")
+	}
+	if spec.HTML == "" && spec.Plain == "" && spec.PlainText == "" && spec.TemplateID == "" {
+		errs = append(errs, "html: required unless plain, plain_text or template_id is given")
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &spec, nil
+}
+
+func hasRule(rules []string, rule string) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// Describes the expected JSON type of the spec field.
+func specType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice:
+		return "a list of strings"
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.String {
+			return "an object with string values"
+		}
+		return "an object"
+	}
+	if t == reflect.TypeOf(&specTracking{}) {
+		return "an object with click_tracking, open_tracking, subscription_tracking and ganalytics"
+	}
+	if t == reflect.TypeOf(sendTime("")) {
+		return "a UNIX timestamp or RFC 3339 time"
+	}
+	return "a string"
+}
+
+// Validates the address or the list of the addresses of the spec field.
+func specAddressErrors(key string, v reflect.Value) []string {
+	var errs []string
+	check := func(name, address string) {
+		if address != "" && !strings.Contains(address, "@") {
+			errs = append(errs, fmt.Sprintf("%s: incorrect address %q", name, address))
+		}
+	}
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			check(fmt.Sprintf("%s[%d]", key, i), v.Index(i).String())
+		}
+	} else {
+		check(key, v.String())
+	}
+	return errs
+}
+
+// Applies the message spec to the flags not given on the command line.
+func applyMessageSpec(cmd *cobra.Command, spec *messageSpec) error {
+	values := map[string][]string{
+		"from": {spec.From}, "to": spec.To, "cc": spec.Cc, "bcc": spec.Bcc, "reply-to": {spec.ReplyTo},
+		"subject": {spec.Subject}, "html": {spec.HTML}, "plain": {spec.Plain}, "plain-text": {spec.PlainText},
+		"template-id": {spec.TemplateID}, "send-at": {string(spec.SendAt)}, "att": spec.Attachments,
+	}
+	if len(spec.Data) > 0 {
+		data, err := json.Marshal(spec.Data)
+		if err != nil {
+			return err
+		}
+		values["data"] = []string{string(data)}
+	}
+	names := make([]string, 0, len(spec.Headers))
+	for name := range spec.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values["header"] = append(values["header"], name+": "+spec.Headers[name])
+	}
+	for flag, list := range values {
+		if cmd.Flags().Changed(flag) {
+			continue
+		}
+		for _, value := range list {
+			if value == "" {
+				continue
+			}
+			if err := cmd.Flags().Set(flag, value); err != nil {
+				return fmt.Errorf("incorrect %q in the message spec: %v", flag, err)
+			}
+		}
+	}
+	return nil
+}
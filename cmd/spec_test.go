@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestParseMessageSpecErrors(t *testing.T) {
+	_, err := parseMessageSpec([]byte(`{
+		"from": "Sender",
+		"cc": ["cc@example.com", "nobody"],
+		"headers": ["X-Campaign"],
+		"send_at": "tomorrow",
+		"bodyy": "<p>Hello</p>"
+	}`))
+	errs, ok := err.(specErrors)
+	if !ok {
+		t.Fatalf("Expected the validation errors, got %v", err)
+	}
+	expected := specErrors{
+		`from: incorrect address "Sender"`,
+		"to: required",
+		`cc[1]: incorrect address "nobody"`,
+		"headers: expected an object with string values",
+		"bodyy: unknown key",
+		`send_at: incorrect send time "tomorrow", expected UNIX timestamp or RFC 3339 time`,
+		"subject: required unless template_id is given",
+		"html: required unless plain, plain_text or template_id is given",
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Expected the errors:\n%v\ngot:\n%v", expected, errs)
+	}
+
+	if _, err := parseMessageSpec([]byte(`["to@example.com"]`)); err == nil {
+		t.Error("Expected an error for a JSON array")
+	}
+	if _, err := parseMessageSpec([]byte(`{"to": []}`)); err == nil || err.(specErrors)[0] != "to: required" {
+		t.Errorf("Expected the empty TO list to be rejected, got %v", err)
+	}
+}
+
+func TestReadMessageSpec(t *testing.T) {
+	filename := tempFile(t, `{"to": ["to@example.com"], "template_id": "TEMPLATE-ID",
+		"html": "body.html", "send_at": 1500000000, "data": {"name": "John"}}`)
+	defer os.Remove(filename)
+
+	spec, err := readMessageSpec(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.HTML != filepath.Join(filepath.Dir(filename), "body.html") || spec.SendAt != "1500000000" ||
+		spec.Data["name"] != "John" || spec.To[0] != "to@example.com" {
+		t.Errorf("Unexpected spec: %+v", spec)
+	}
+}
+
+func TestTemplateOnlySpec(t *testing.T) {
+	filename := tempFile(t, `{"to": ["to@example.com"], "template_id": "TEMPLATE-ID"}`)
+	defer os.Remove(filename)
+	// The spec recipients get appended to the string array flag, so use a scratch one
+	to := RootCmd.PersistentFlags().Lookup("to")
+	defer func(v pflag.Value) { to.Value, to.Changed = v, false }(to.Value)
+	scratch := pflag.NewFlagSet("scratch", pflag.ContinueOnError)
+	scratch.StringArray("to", nil, "")
+	to.Value = scratch.Lookup("to").Value
+	defer func(w io.Writer) { stdout = w }(stdout)
+	var out bytes.Buffer
+	stdout = &out
+	defer func() {
+		for name, value := range map[string]string{"key": "", "from-file": "", "template-id": "", "dry-run": "false"} {
+			RootCmd.PersistentFlags().Set(name, value)
+			RootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+	}()
+	RootCmd.SetArgs([]string{"-k", "API-KEY", "--from-file", filename, "--dry-run"})
+	captureLog(func() {
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out.String(), `"template_id": "TEMPLATE-ID"`) {
+		t.Errorf("Expected the spec template in the message, got:\n%s", out.String())
+	}
+}
+
+func TestMessageSpecTrackingSettings(t *testing.T) {
+	filename := tempFile(t, `{"to": ["to@example.com"], "subject": "Test", "plain_text": "Test",
+		"tracking_settings": {
+			"click_tracking": {"enable": false},
+			"open_tracking": {"enable": true, "substitution_tag": "%open%"},
+			"subscription_tracking": {"enable": true, "text": "Unsubscribe: <% %>"},
+			"ganalytics": {"enable": true, "utm_source": "newsletter", "utm_campaign": "spring"}
+		}}`)
+	defer os.Remove(filename)
+
+	spec, err := readMessageSpec(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              spec.To,
+		subject:          spec.Subject,
+		plainTextContent: spec.PlainText,
+		trackingSettings: spec.Tracking.settings(),
+	})
+	ts := message.TrackingSettings
+	if ts == nil || ts.ClickTracking == nil || *ts.ClickTracking.Enable || ts.OpenTracking.SubstitutionTag != "%open%" ||
+		ts.SubscriptionTracking.Text != "Unsubscribe: <% %>" || ts.GoogleAnalytics.CampaignName != "spring" {
+		t.Fatalf("Expected the tracking settings of the spec, got %+v", ts)
+	}
+
+	message = newV3Message(&sendParams{
+		from:             "sender@example.com",
+		tos:              spec.To,
+		subject:          spec.Subject,
+		plainTextContent: spec.PlainText,
+		trackingSettings: spec.Tracking.settings(),
+		noTracking:       true,
+	})
+	if ts := message.TrackingSettings; *ts.OpenTracking.Enable || ts.GoogleAnalytics.CampaignSource != "newsletter" {
+		t.Errorf("Expected --no-tracking to disable the open tracking keeping the rest, got %+v", ts)
+	}
+
+	_, err = parseMessageSpec([]byte(`{"to": ["to@example.com"], "subject": "Test", "plain_text": "Test",
+		"tracking_settings": {"sandbox_mode": {"enable": true}}}`))
+	if errs, ok := err.(specErrors); !ok || len(errs) != 1 || !strings.HasPrefix(errs[0], "tracking_settings: expected an object") {
+		t.Errorf("Expected the unknown tracking setting to be rejected, got %v", err)
+	}
+}
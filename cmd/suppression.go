@@ -0,0 +1,222 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// suppressionCmd represents the suppression command
+var suppressionCmd = &cobra.Command{
+	Use:   "suppression",
+	Short: "Manage suppression lists",
+}
+
+// suppressionBouncesCmd represents the suppression bounces command
+var suppressionBouncesCmd = &cobra.Command{
+	Use:   "bounces",
+	Short: "List bounced addresses",
+	Long: `Lists the bounced addresses with the bounce reasons classified as hard or soft
+bounces and by the cause, eg, mailbox-full or domain-not-found, eg,
+
+sendgrid-cli suppression bounces -k API-KEY
+`,
+	Run: suppressionBounces,
+}
+
+func init() {
+	RootCmd.AddCommand(suppressionCmd)
+	suppressionCmd.AddCommand(suppressionBouncesCmd)
+}
+
+// Bounced address
+type bounce struct {
+	Created int64  `json:"created"`
+	Email   string `json:"email"`
+	Reason  string `json:"reason"`
+	Status  string `json:"status"`
+	Class   string `json:"class"` // classification of the reason, eg, "soft/mailbox-full"
+}
+
+func suppressionBounces(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	bounces, err := fetchBounces(apiKeyFlag(cmd))
+	if err != nil {
+		log.Error("Failed to retrieve the bounces.")
+		fail(errorExitCode(err), err)
+	}
+
+	rows := make([][]string, len(bounces))
+	for i, b := range bounces {
+		created := time.Unix(b.Created, 0).UTC().Format(time.RFC3339)
+		rows[i] = []string{b.Email, created, b.Status, b.Class, b.Reason}
+	}
+	err = printOutput(cmd, bounces, []string{"EMAIL", "CREATED", "STATUS", "CLASS", "REASON"}, rows)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+}
+
+// Retrieves all the bounces page by page and classifies their reasons.
+func fetchBounces(apiKey string) ([]bounce, error) {
+	bounces := []bounce{}
+	for offset := 0; ; offset += pageSize {
+		var page []bounce
+		err := apiGet(apiKey, "/v3/suppression/bounces", map[string]string{
+			"limit":  strconv.Itoa(pageSize),
+			"offset": strconv.Itoa(offset),
+		}, &page)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page {
+			page[i].Class = classifyBounce(page[i].Reason, page[i].Status)
+		}
+		bounces = append(bounces, page...)
+		if len(page) < pageSize {
+			return bounces, nil
+		}
+	}
+}
+
+// Suppression lists checked before sending with --abort-on-suppressed or --skip-suppressed
+var suppressionLists = []string{"bounces", "blocks", "spam_reports", "unsubscribes"}
+
+// Retrieves all the suppression lists page by page returning the list name
+// of every suppressed address keyed by the lowercased address.
+func fetchSuppressed(apiKey string) (map[string]string, error) {
+	suppressed := make(map[string]string)
+	for _, list := range suppressionLists {
+		for offset := 0; ; offset += pageSize {
+			var page []struct {
+				Email string `json:"email"`
+			}
+			err := apiGet(apiKey, "/v3/suppression/"+list, map[string]string{
+				"limit":  strconv.Itoa(pageSize),
+				"offset": strconv.Itoa(offset),
+			}, &page)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range page {
+				if _, ok := suppressed[strings.ToLower(s.Email)]; !ok {
+					suppressed[strings.ToLower(s.Email)] = list
+				}
+			}
+			if len(page) < pageSize {
+				break
+			}
+		}
+	}
+	return suppressed, nil
+}
+
+// Removes the suppressed addresses from the recipient list returning
+// the removed ones as "address (list)".
+func removeSuppressed(suppressed map[string]string, addresses []string) (kept, removed []string) {
+	for _, raw := range addresses {
+		if list, ok := suppressed[strings.ToLower(createAddress(raw).Address)]; ok {
+			removed = append(removed, fmt.Sprintf("%s (%s)", raw, list))
+		} else {
+			kept = append(kept, raw)
+		}
+	}
+	return kept, removed
+}
+
+// Checks the recipients against the suppressed addresses. Unless skipping, fails
+// if any recipient is suppressed, otherwise removes the suppressed recipients.
+func checkSuppressed(suppressed map[string]string, skip bool, tos, ccs, bccs []string) ([]string, []string, []string, error) {
+	var removed, r []string
+	tos, r = removeSuppressed(suppressed, tos)
+	removed = append(removed, r...)
+	ccs, r = removeSuppressed(suppressed, ccs)
+	removed = append(removed, r...)
+	bccs, r = removeSuppressed(suppressed, bccs)
+	removed = append(removed, r...)
+	if len(removed) == 0 {
+		return tos, ccs, bccs, nil
+	}
+	if !skip {
+		return nil, nil, nil, fmt.Errorf("the suppressed recipients: %s (use --skip-suppressed to skip them)",
+			strings.Join(removed, ", "))
+	}
+	log.Warnf("Skipping the suppressed recipients: %s", strings.Join(removed, ", "))
+	if len(tos) == 0 {
+		return nil, nil, nil, errors.New("all the TO recipients are suppressed")
+	}
+	return tos, ccs, bccs, nil
+}
+
+// Bounce causes recognized by the reason text, checked in order
+var bounceCauses = []struct {
+	cause string
+	hard  bool
+	expr  *regexp.Regexp
+}{
+	{"mailbox-full", false, regexp.MustCompile(
+		`(?i)mailbox (is )?full|over ?quota|quota exceeded|insufficient (system )?storage|\b[45]\.2\.2\b`)},
+	{"domain-not-found", true, regexp.MustCompile(
+		`(?i)domain (name )?(not found|does not exist)|no such domain|host (or domain name )?not found|` +
+			`unrouteable|no mx|nxdomain|\b5\.1\.2\b`)},
+	{"mailbox-not-found", true, regexp.MustCompile(
+		`(?i)user unknown|unknown user|no such (user|recipient|mailbox)|invalid (recipient|mailbox)|` +
+			`mailbox (not found|unavailable|does not exist)|address rejected|does not exist|\b5\.1\.1\b`)},
+	{"blocked", false, regexp.MustCompile(
+		`(?i)blocked|block ?list|black ?list|spam|reputation|rejected for policy|\b5\.7\.1\b`)},
+	{"connection", false, regexp.MustCompile(
+		`(?i)timed? ?out|connection (refused|reset|lost)|try again later|temporar(y|ily)`)},
+}
+
+// Enhanced status code (RFC 3463), eg, 5.1.1
+var enhancedStatusCode = regexp.MustCompile(`\b([245])\.\d{1,3}\.\d{1,3}\b`)
+
+// Classifies the bounce by its reason and status as "hard/<cause>" or
+// "soft/<cause>". The reasons of unknown cause are classified by the status
+// code as "hard/other" (5.x.x) or "soft/other" (4.x.x), otherwise as "unknown".
+func classifyBounce(reason, status string) string {
+	for _, c := range bounceCauses {
+		if c.expr.MatchString(reason) {
+			if c.hard {
+				return "hard/" + c.cause
+			}
+			return "soft/" + c.cause
+		}
+	}
+	class := ""
+	if code := enhancedStatusCode.FindStringSubmatch(status + " " + reason); code != nil {
+		class = code[1]
+	} else if reason = strings.TrimSpace(reason); reason != "" {
+		class = reason[:1] // the basic SMTP reply code, eg, 550
+	}
+	switch class {
+	case "5":
+		return "hard/other"
+	case "4":
+		return "soft/other"
+	}
+	return "unknown"
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClassifyBounce(t *testing.T) {
+	for _, c := range []struct{ reason, status, expected string }{
+		{"452 4.2.2 The email account that you tried to reach is over quota.", "4.2.2", "soft/mailbox-full"},
+		{"552 Mailbox is full", "", "soft/mailbox-full"},
+		{"Unable to resolve MX host example.invalid: no such domain", "5.1.2", "hard/domain-not-found"},
+		{"550 5.1.1 The email account that you tried to reach does not exist.", "5.1.1", "hard/mailbox-not-found"},
+		{"550 Requested action not taken: mailbox unavailable", "5.0.0", "hard/mailbox-not-found"},
+		{"554 5.7.1 Message rejected because of spam content", "5.7.1", "soft/blocked"},
+		{"421 Connection timed out", "4.0.0", "soft/connection"},
+		{"550 Administrative prohibition", "5.0.0", "hard/other"},
+		{"451 Please come back later", "", "soft/other"},
+		{"Something happened", "", "unknown"},
+	} {
+		if class := classifyBounce(c.reason, c.status); class != c.expected {
+			t.Errorf("Expected %q for %q, got %q", c.expected, c.reason, class)
+		}
+	}
+}
+
+func TestFetchBounces(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/suppression/bounces" {
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+		fmt.Fprintln(w, `[{"created": 1443651125, "email": "a@example.com", "reason": "550 5.1.1 User unknown", "status": "5.1.1"},
+			{"created": 1443651141, "email": "b@example.com", "reason": "552 Mailbox is full", "status": "5.2.2"}]`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	bounces, err := fetchBounces("API-KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bounces) != 2 || bounces[0].Class != "hard/mailbox-not-found" || bounces[1].Class != "soft/mailbox-full" {
+		t.Errorf("Unexpected bounces: %+v", bounces)
+	}
+}
+
+func TestCheckSuppressed(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/suppression/bounces":
+			fmt.Fprintln(w, `[{"email": "Bounced@example.com", "reason": "550 5.1.1 User unknown"}]`)
+		case "/v3/suppression/unsubscribes":
+			fmt.Fprintln(w, `[{"email": "gone@example.com"}]`)
+		default:
+			fmt.Fprintln(w, `[]`)
+		}
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	suppressed, err := fetchSuppressed("API-KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tos := []string{"ok@example.com", "bounced@example.com"}
+	ccs := []string{"Gone <gone@example.com>"}
+	if _, _, _, err := checkSuppressed(suppressed, false, tos, ccs, nil); err == nil ||
+		!strings.Contains(err.Error(), "bounced@example.com (bounces), Gone <gone@example.com> (unsubscribes)") {
+		t.Errorf("Expected the abort listing the suppressed recipients, got %v", err)
+	}
+
+	output := captureLog(func() {
+		tos, ccs, _, err = checkSuppressed(suppressed, true, tos, ccs, nil)
+	})
+	if err != nil || !reflect.DeepEqual(tos, []string{"ok@example.com"}) || len(ccs) != 0 {
+		t.Errorf("Expected the suppressed recipients to be skipped, got %v, %v (%v)", tos, ccs, err)
+	}
+	if !strings.Contains(output, "Skipping the suppressed recipients") {
+		t.Errorf("Expected a warning about the skipped recipients, got %q", output)
+	}
+	if _, _, _, err := checkSuppressed(suppressed, true, []string{"bounced@example.com"}, nil, nil); err == nil {
+		t.Error("Expected an error if all the TO recipients are suppressed")
+	}
+}
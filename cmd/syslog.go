@@ -0,0 +1,79 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows && !nacl && !plan9
+// +build !windows,!nacl,!plan9
+
+package cmd
+
+import (
+	"fmt"
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Logrus hook forwarding the log messages to syslog
+type syslogHook struct {
+	writer    *syslog.Writer
+	formatter log.Formatter
+}
+
+// Connects to the syslog given as "network:host:port", eg, udp:localhost:514,
+// or to the local syslog if the address is empty. The messages get tagged with
+// the program name.
+func newSyslogHook(address string) (log.Hook, error) {
+	var network, raddr string
+	if address != "" {
+		parts := strings.SplitN(address, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("incorrect syslog address %q, expected network:host:port", address)
+		}
+		network, raddr = parts[0], parts[1]
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, filepath.Base(os.Args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return &syslogHook{
+		writer:    w,
+		formatter: &log.TextFormatter{DisableColors: true, DisableTimestamp: true},
+	}, nil
+}
+
+func (h *syslogHook) Fire(entry *log.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	message := strings.TrimSpace(string(line))
+	switch entry.Level {
+	case log.PanicLevel, log.FatalLevel:
+		return h.writer.Crit(message)
+	case log.ErrorLevel:
+		return h.writer.Err(message)
+	case log.WarnLevel:
+		return h.writer.Warning(message)
+	case log.InfoLevel:
+		return h.writer.Info(message)
+	}
+	return h.writer.Debug(message)
+}
+
+func (h *syslogHook) Levels() []log.Level {
+	return log.AllLevels
+}
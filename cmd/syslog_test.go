@@ -0,0 +1,68 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows && !nacl && !plan9
+// +build !windows,!nacl,!plan9
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestSyslogHook(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	hook, err := newSyslogHook("udp:" + conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	logger.Hooks.Add(hook)
+	logger.WithField("batch", 2).Warn("Failed to send the batch")
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := string(buf[:n])
+	// <PRI> of the warning with the user facility is 1*8+4:
+	if !strings.HasPrefix(message, "<12>") {
+		t.Errorf("Expected the warning priority, got %q", message)
+	}
+	for _, part := range []string{"cmd.test", `msg="Failed to send the batch"`, "batch=2"} {
+		if !strings.Contains(message, part) {
+			t.Errorf("Expected %q in the syslog message %q", part, message)
+		}
+	}
+}
+
+func TestSyslogHookIncorrectAddress(t *testing.T) {
+	if _, err := newSyslogHook("localhost"); err == nil {
+		t.Error("Expected an error for the address without the network")
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// templatesCmd represents the templates command
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Manage transactional templates",
+}
+
+// templatesListCmd represents the templates list command
+var templatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List transactional templates",
+	Long: `Lists the legacy and dynamic transactional templates of the account with
+their IDs, names, generations and the active version names, eg,
+
+sendgrid-cli templates list -k API-KEY --output csv
+`,
+	Run: templatesList,
+}
+
+func init() {
+	RootCmd.AddCommand(templatesCmd)
+	templatesCmd.AddCommand(templatesListCmd)
+}
+
+func templatesList(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	templates, err := fetchTemplates(apiKeyFlag(cmd))
+	if err != nil {
+		log.Error("Failed to retrieve the templates.")
+		fail(errorExitCode(err), err)
+	}
+
+	rows := make([][]string, len(templates))
+	for i, t := range templates {
+		active := ""
+		if v := t.activeVersion(); v != nil {
+			active = v.Name
+		}
+		rows[i] = []string{t.ID, t.Name, t.Generation, active}
+	}
+	err = printOutput(cmd, templates, []string{"ID", "NAME", "GENERATION", "ACTIVE VERSION"}, rows)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+}
+
+// SendGrid transactional template
+type transactionalTemplate struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Generation string            `json:"generation"`
+	Versions   []templateVersion `json:"versions"`
+}
+
+// Version of the transactional template
+type templateVersion struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Subject      string `json:"subject"`
+	Active       int    `json:"active"`
+	HTMLContent  string `json:"html_content"`
+	PlainContent string `json:"plain_content"`
+}
+
+// Retrieves the transactional template by its ID.
+func fetchTemplate(apiKey, id string) (*transactionalTemplate, error) {
+	var t transactionalTemplate
+	err := apiGet(apiKey, "/v3/templates/"+url.PathEscape(id), nil, &t)
+	if e, ok := err.(*APIError); ok && e.StatusCode == http.StatusNotFound {
+		e.Body = fmt.Sprintf("template %q not found", id)
+		return nil, e
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Maximum page size of the templates listing
+const templatesPageSize = 200
+
+// Retrieves the legacy and dynamic transactional templates following the
+// pages of the listing.
+func fetchTemplates(apiKey string) ([]transactionalTemplate, error) {
+	params := map[string]string{
+		"generations": "legacy,dynamic",
+		"page_size":   strconv.Itoa(templatesPageSize),
+	}
+	var templates []transactionalTemplate
+	for {
+		var result struct {
+			Result    []transactionalTemplate `json:"result"`
+			Templates []transactionalTemplate `json:"templates"` // the legacy response
+			Metadata  struct {
+				Next string `json:"next"`
+			} `json:"_metadata"`
+		}
+		if err := apiGet(apiKey, "/v3/templates", params, &result); err != nil {
+			return nil, err
+		}
+		templates = append(append(templates, result.Result...), result.Templates...)
+		next, err := url.Parse(result.Metadata.Next)
+		if err != nil {
+			return nil, err
+		}
+		token := next.Query().Get("page_token")
+		if token == "" || token == params["page_token"] {
+			return templates, nil
+		}
+		params["page_token"] = token
+	}
+}
+
+// Resolves the template ID by the template name.
+func templateIDByName(apiKey, name string) (string, error) {
+	templates, err := fetchTemplates(apiKey)
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for _, t := range templates {
+		if t.Name == name {
+			ids = append(ids, t.ID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("template %q not found", name)
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("the template name %q is ambiguous, use --template-id with one of: %s",
+		name, strings.Join(ids, ", "))
+}
+
+// Parses the dynamic template data given as a JSON object or @FILENAME.
+func parseTemplateData(raw string) (map[string]interface{}, error) {
+	b := []byte(raw)
+	if strings.HasPrefix(raw, "@") {
+		var err error
+		if b, err = ioutil.ReadFile(raw[1:]); err != nil {
+			return nil, err
+		}
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("the dynamic template data should be a JSON object: %v", err)
+	}
+	return data, nil
+}
+
+// Returns the active version of the template.
+func (t *transactionalTemplate) activeVersion() *templateVersion {
+	for i := range t.Versions {
+		if t.Versions[i].Active == 1 {
+			return &t.Versions[i]
+		}
+	}
+	return nil
+}
+
+var (
+	handlebarsExpr    = regexp.MustCompile(`\{\{\{?~?\s*([^}]*?)\s*~?\}?\}\}`)
+	handlebarsKeyword = map[string]bool{
+		"if": true, "unless": true, "each": true, "with": true, "else": true, "this": true,
+	}
+)
+
+// Extracts the top-level data keys referenced in the handlebars template.
+// The references inside #each blocks are relative to the iterated items,
+// so only their "@root." references are taken into account.
+func templateVariables(source string) []string {
+	vars := make(map[string]bool)
+	eachDepth := 0
+	for _, m := range handlebarsExpr.FindAllStringSubmatch(source, -1) {
+		fields := strings.Fields(m[1])
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "!") || strings.HasPrefix(fields[0], ">") {
+			continue
+		}
+		if strings.HasPrefix(fields[0], "/") {
+			if fields[0] == "/each" && eachDepth > 0 {
+				eachDepth--
+			}
+			continue
+		}
+		block := strings.TrimPrefix(fields[0], "#")
+		names := fields
+		if len(fields) > 1 || block != fields[0] {
+			names = fields[1:] // the arguments of a helper
+		}
+		for _, name := range names {
+			if i := strings.Index(name, "="); i >= 0 {
+				name = name[i+1:] // hash argument
+			}
+			if eachDepth > 0 && !strings.HasPrefix(name, "@root.") {
+				continue
+			}
+			name = strings.TrimPrefix(name, "@root.")
+			name = strings.Trim(strings.SplitN(strings.SplitN(name, ".", 2)[0], "[", 2)[0], "()")
+			if name == "" || handlebarsKeyword[name] || strings.ContainsAny(name[:1], "\"'@0123456789-") ||
+				name == "true" || name == "false" {
+				continue
+			}
+			vars[name] = true
+		}
+		if block == "each" && block != fields[0] {
+			eachDepth++
+		}
+	}
+	return sortedKeys(vars)
+}
+
+// Compares the keys referenced in the template with the supplied data keys.
+func lintTemplateData(source string, data map[string]interface{}) (missing, unused []string) {
+	referenced := make(map[string]bool)
+	for _, v := range templateVariables(source) {
+		referenced[v] = true
+		if _, ok := data[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	for k := range data {
+		if !referenced[k] {
+			unused = append(unused, k)
+		}
+	}
+	sort.Strings(unused)
+	return
+}
+
+// Fetches the template and returns the warnings about the mismatched data keys.
+func templateDataWarnings(apiKey, templateID string, data map[string]interface{}) ([]string, error) {
+	t, err := fetchTemplate(apiKey, templateID)
+	if err != nil {
+		return nil, err
+	}
+	v := t.activeVersion()
+	if v == nil {
+		return []string{fmt.Sprintf("The template %q has no active version", templateID)}, nil
+	}
+	var warnings []string
+	missing, unused := lintTemplateData(v.Subject+"\n"+v.HTMLContent+"\n"+v.PlainContent, data)
+	for _, k := range missing {
+		warnings = append(warnings, fmt.Sprintf("The template references %q, but it is not supplied in --data", k))
+	}
+	for _, k := range unused {
+		warnings = append(warnings, fmt.Sprintf("The key %q is supplied in --data, but not used in the template", k))
+	}
+	return warnings, nil
+}
+
+// Template generations
+const (
+	legacyTemplate  = "legacy"
+	dynamicTemplate = "dynamic"
+)
+
+// Returns the warnings about the substitution mechanism not matching the
+// generation of the template: the legacy templates use the substitutions
+// (--sub), the dynamic ones the handlebars data (--data).
+func templateGenerationWarnings(t *transactionalTemplate, subs []string, data map[string]interface{}) []string {
+	var warnings []string
+	switch t.Generation {
+	case dynamicTemplate:
+		if len(subs) > 0 {
+			warnings = append(warnings, fmt.Sprintf(
+				"The template %q is dynamic, the substitutions given with --sub are ignored, use --data instead", t.ID))
+		}
+	case legacyTemplate:
+		if len(data) > 0 {
+			warnings = append(warnings, fmt.Sprintf(
+				"The template %q is legacy, the data given with --data is ignored, use --sub instead", t.ID))
+		}
+	}
+	return warnings
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchTemplate(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/templates/TEMPLATE-ID" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"error": "NOT FOUND"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"id": "TEMPLATE-ID", "name": "Welcome", "generation": "legacy"}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	template, err := fetchTemplate("API-KEY", "TEMPLATE-ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.Name != "Welcome" {
+		t.Errorf("Unexpected template: %+v", template)
+	}
+
+	_, err = fetchTemplate("API-KEY", "TYPO")
+	if err == nil || !strings.Contains(err.Error(), "template \"TYPO\" not found") {
+		t.Errorf("Expected 'template not found' error, got: %v", err)
+	}
+	if errorExitCode(err) != exitAPI {
+		t.Errorf("Expected the missing template to be classified as API error")
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	source := `<h1>Hello {{ first_name }} {{{user.last_name}}}!</h1>
+{{!-- comment --}}
+{{#if promo}}<p>{{formatDate expires "2006-01-02"}}</p>{{else if fallback}}{{/if}}
+{{#each items}}<li>{{this.name}} {{price}} {{@root.currency}}</li>{{/each}}
+{{> footer}}`
+	expected := []string{"currency", "expires", "fallback", "first_name", "items", "promo", "user"}
+	if vars := templateVariables(source); strings.Join(vars, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected variables %v, got %v", expected, vars)
+	}
+}
+
+func TestTemplateDataWarnings(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"id": "TEMPLATE-ID", "generation": "dynamic", "versions": [
+			{"active": 0, "html_content": "{{old}}"},
+			{"active": 1, "subject": "Hi {{name}}", "html_content": "<p>{{order_id}}</p>"}]}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	warnings, err := templateDataWarnings("API-KEY", "TEMPLATE-ID",
+		map[string]interface{}{"name": "John", "unused_key": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`The template references "order_id", but it is not supplied in --data`,
+		`The key "unused_key" is supplied in --data, but not used in the template`,
+	}
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected warnings:\n%v\ngot:\n%v", expected, warnings)
+	}
+}
+
+func TestTemplatesListCSV(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/templates" || r.URL.Query().Get("generations") != "legacy,dynamic" {
+			t.Errorf("Unexpected request %q", r.URL)
+		}
+		fmt.Fprintln(w, `{"result": [{"id": "T-1", "name": "Welcome, new user", "generation": "dynamic",
+			"versions": [{"name": "v2", "active": 1}]}, {"id": "T-2", "name": "Legacy", "generation": "legacy"}]}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, w io.Writer) { apiHost, stdout = h, w }(apiHost, stdout)
+	var out bytes.Buffer
+	apiHost, stdout = fakeServer.URL, &out
+
+	RootCmd.SetArgs([]string{"templates", "list", "-k", "API-KEY", "--output", "csv"})
+	defer RootCmd.PersistentFlags().Set("output", outputTable)
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "ID,NAME,GENERATION,ACTIVE VERSION\nT-1,\"Welcome, new user\",dynamic,v2\nT-2,Legacy,legacy,\n"
+	if out.String() != expected {
+		t.Errorf("Expected CSV output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestTemplateIDByName(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"result": [{"id": "T-1", "name": "Welcome"}, {"id": "T-2", "name": "Receipt"},
+			{"id": "T-3", "name": "Reminder"}, {"id": "T-4", "name": "Reminder"}]}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	if id, err := templateIDByName("API-KEY", "Receipt"); err != nil || id != "T-2" {
+		t.Errorf("Expected the template ID T-2, got %q (%v)", id, err)
+	}
+	if _, err := templateIDByName("API-KEY", "Missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected the template not to be found, got %v", err)
+	}
+	if _, err := templateIDByName("API-KEY", "Reminder"); err == nil || !strings.Contains(err.Error(), "T-3, T-4") {
+		t.Errorf("Expected the ambiguity error, got %v", err)
+	}
+}
+
+func TestTemplateIDByNamePages(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page_token") {
+		case "":
+			fmt.Fprintln(w, `{"result": [{"id": "T-1", "name": "Welcome"}, {"id": "T-2", "name": "Reminder"}],
+				"_metadata": {"next": "https://api.sendgrid.com/v3/templates?page_token=PAGE-2&page_size=200"}}`)
+		case "PAGE-2":
+			fmt.Fprintln(w, `{"result": [{"id": "T-3", "name": "Receipt"}, {"id": "T-4", "name": "Reminder"}],
+				"_metadata": {"self": "https://api.sendgrid.com/v3/templates?page_token=PAGE-2&page_size=200"}}`)
+		default:
+			t.Errorf("Unexpected page %q", r.URL.RawQuery)
+		}
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	if id, err := templateIDByName("API-KEY", "Receipt"); err != nil || id != "T-3" {
+		t.Errorf("Expected the template ID T-3 from the second page, got %q (%v)", id, err)
+	}
+	if _, err := templateIDByName("API-KEY", "Reminder"); err == nil || !strings.Contains(err.Error(), "T-2, T-4") {
+		t.Errorf("Expected the ambiguity error across the pages, got %v", err)
+	}
+}
+
+func TestTemplateGenerationWarnings(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/templates/DYNAMIC-ID":
+			fmt.Fprintln(w, `{"id": "DYNAMIC-ID", "generation": "dynamic"}`)
+		default:
+			fmt.Fprintln(w, `{"id": "LEGACY-ID", "generation": "legacy"}`)
+		}
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	dynamic, err := fetchTemplate("API-KEY", "DYNAMIC-ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := templateGenerationWarnings(dynamic, []string{"name=John"}, nil)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "--sub are ignored") {
+		t.Errorf("Expected the warning about --sub on the dynamic template, got %v", warnings)
+	}
+	if warnings := templateGenerationWarnings(dynamic, nil, map[string]interface{}{"name": "John"}); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for --data on the dynamic template, got %v", warnings)
+	}
+
+	legacy, err := fetchTemplate("API-KEY", "LEGACY-ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings = templateGenerationWarnings(legacy, nil, map[string]interface{}{"name": "John"})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "--data is ignored") {
+		t.Errorf("Expected the warning about --data on the legacy template, got %v", warnings)
+	}
+	if warnings := templateGenerationWarnings(legacy, []string{"name=John"}, nil); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for --sub on the legacy template, got %v", warnings)
+	}
+}
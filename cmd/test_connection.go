@@ -0,0 +1,129 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// testConnectionCmd represents the test-connection command
+var testConnectionCmd = &cobra.Command{
+	Use:   "test-connection",
+	Short: "Test the connectivity and the authentication with SendGrid API",
+	Long: `Makes a lightweight authenticated request to SendGrid API without sending any mail
+and reports the response status, the latency and whether the API key is valid, eg,
+
+sendgrid-cli test-connection -k API-KEY --timeout 5s
+
+Exits with the non-zero code if the API is unreachable or the API key is invalid.
+
+With --format nagios it can be used as a monitoring probe: it prints the
+OK, WARNING (the latency above --warning-latency) or CRITICAL status line
+with the latency perfdata and exits with 0, 1 or 2 respectively, eg,
+
+sendgrid-cli test-connection -k API-KEY --format nagios --warning-latency 2s
+`,
+	Run: testConnection,
+}
+
+func init() {
+	RootCmd.AddCommand(testConnectionCmd)
+	testConnectionCmd.Flags().String("format", "", "Output format of the monitoring probe: nagios.")
+	testConnectionCmd.Flags().Duration("warning-latency", time.Second,
+		"Latency above which the nagios status is WARNING.")
+}
+
+// Nagios plugin exit codes
+const (
+	nagiosOK       = 0
+	nagiosWarning  = 1
+	nagiosCritical = 2
+)
+
+// Result of the connection test
+type connectionCheck struct {
+	StatusCode int           `json:"status_code"`
+	Latency    time.Duration `json:"latency_ns"`
+	KeyValid   bool          `json:"key_valid"`
+	Error      string        `json:"error,omitempty"`
+}
+
+func testConnection(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	check, err := checkConnection(apiKeyFlag(cmd))
+
+	switch format := flagString(cmd, "format"); format {
+	case "":
+	case "nagios":
+		status, code := nagiosStatus(check, flagDuration(cmd, "warning-latency"))
+		fmt.Fprintln(stdout, status)
+		exit(code)
+		return
+	default:
+		failf(exitUsage, "Unsupported format %q, use nagios.", format)
+	}
+	if outputFormat(cmd) == outputJSON {
+		if e := printJSON(stdout, check); e != nil {
+			fail(exitUsage, e)
+		}
+	} else if check.StatusCode == 0 {
+		fmt.Fprintf(stdout, "Unreachable: %s (after %v)\n", check.Error, check.Latency)
+	} else {
+		fmt.Fprintf(stdout, "Status: %d, latency: %v, API key valid: %t\n",
+			check.StatusCode, check.Latency, check.KeyValid)
+	}
+	if err != nil {
+		fail(errorExitCode(err), err)
+	}
+}
+
+// Requests the scopes of the API key and measures the latency.
+func checkConnection(apiKey string) (*connectionCheck, error) {
+	started := time.Now()
+	response, err := apiRequest(apiKey, rest.Get, "/v3/scopes", nil, nil)
+	check := &connectionCheck{Latency: time.Since(started).Round(time.Millisecond)}
+	if response != nil {
+		check.StatusCode = response.StatusCode
+		check.KeyValid = response.StatusCode < 300
+	}
+	if e, ok := err.(*APIError); ok &&
+		(e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden) {
+		e.Body = "the API key is invalid or lacks the access: " + e.Body
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check, err
+}
+
+// Returns the nagios status line with the latency perfdata and the exit code.
+func nagiosStatus(check *connectionCheck, warningLatency time.Duration) (string, int) {
+	perfdata := fmt.Sprintf("latency=%.3fs;%.3f;;0", check.Latency.Seconds(), warningLatency.Seconds())
+	switch {
+	case check.Error != "":
+		return fmt.Sprintf("SENDGRID CRITICAL - %s | %s", check.Error, perfdata), nagiosCritical
+	case warningLatency > 0 && check.Latency > warningLatency:
+		return fmt.Sprintf("SENDGRID WARNING - latency %v above %v | %s", check.Latency, warningLatency, perfdata),
+			nagiosWarning
+	}
+	return fmt.Sprintf("SENDGRID OK - status %d, latency %v | %s", check.StatusCode, check.Latency, perfdata), nagiosOK
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sendgrid/rest"
+)
+
+func TestCheckConnection(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/scopes" {
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer GOOD-KEY" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"errors": [{"message": "authorization required"}]}`)
+			return
+		}
+		fmt.Fprintln(w, `{"scopes": ["mail.send"]}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	check, err := checkConnection("GOOD-KEY")
+	if err != nil || check.StatusCode != http.StatusOK || !check.KeyValid {
+		t.Errorf("Unexpected check result: %+v (%v)", check, err)
+	}
+
+	check, err = checkConnection("BAD-KEY")
+	if err == nil || check.StatusCode != http.StatusUnauthorized || check.KeyValid || errorExitCode(err) != exitAPI {
+		t.Errorf("Expected the invalid key to be reported, got: %+v (%v)", check, err)
+	}
+}
+
+func TestCheckConnectionTimeout(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, d time.Duration) {
+		apiHost, rest.DefaultClient.HTTPClient.Timeout = h, d
+	}(apiHost, rest.DefaultClient.HTTPClient.Timeout)
+	apiHost, rest.DefaultClient.HTTPClient.Timeout = fakeServer.URL, 50*time.Millisecond
+
+	check, err := checkConnection("API-KEY")
+	if err == nil || check.StatusCode != 0 || errorExitCode(err) != exitNetwork {
+		t.Errorf("Expected the timeout to be reported, got: %+v (%v)", check, err)
+	}
+}
+
+func TestTestConnectionNagios(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer GOOD-KEY" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"errors": [{"message": "authorization required"}]}`)
+			return
+		}
+		fmt.Fprintln(w, `{"scopes": ["mail.send"]}`)
+	}))
+	defer fakeServer.Close()
+	defer func(h string, w io.Writer) { apiHost, stdout = h, w }(apiHost, stdout)
+	apiHost = fakeServer.URL
+	defer func() {
+		testConnectionCmd.Flags().Set("format", "")
+		RootCmd.PersistentFlags().Set("key", "")
+		RootCmd.PersistentFlags().Lookup("key").Changed = false
+	}()
+
+	for key, expected := range map[string]struct {
+		status string
+		code   int
+	}{
+		"GOOD-KEY": {"SENDGRID OK - status 200", nagiosOK},
+		"BAD-KEY":  {"SENDGRID CRITICAL - SendGrid API error (401)", nagiosCritical},
+	} {
+		var out bytes.Buffer
+		stdout = &out
+		RootCmd.SetArgs([]string{"test-connection", "-k", key, "--format", "nagios"})
+		expectExit(t, expected.code, func() { RootCmd.Execute() })
+		if !strings.HasPrefix(out.String(), expected.status) || !strings.Contains(out.String(), "| latency=") {
+			t.Errorf("Expected the status %q with the latency perfdata, got %q", expected.status, out.String())
+		}
+	}
+
+	status, code := nagiosStatus(&connectionCheck{StatusCode: 200, KeyValid: true, Latency: 3 * time.Second}, time.Second)
+	if code != nagiosWarning || status != "SENDGRID WARNING - latency 3s above 1s | latency=3.000s;1.000;;0" {
+		t.Errorf("Expected the slow connection warning, got %q (%d)", status, code)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// warmupPlanCmd represents the warmup-plan command
+var warmupPlanCmd = &cobra.Command{
+	Use:   "warmup-plan",
+	Short: "Schedule the message following an IP warmup ramp",
+	Long: `Splits the recipients into the daily batches following the warmup ramp
+(the number of recipients per day, the last one is repeated until all the
+recipients are scheduled), schedules each day's send in its own batch and
+prints the resulting plan, eg,
+
+sendgrid-cli warmup-plan -k API-KEY --ramp 50,100,500 --start 2017-10-02T09:00:00Z \
+	--to-file recipients.txt -s "Newsletter" --html newsletter.html
+
+The message is given with the same options as for sending. Only the days
+within --max-send-at-window get scheduled, rerun the command with the same
+--start and --from-day later to schedule the remaining ones. Use --plan-only
+to print the plan without scheduling anything.
+`,
+	Run: warmupPlan,
+}
+
+func init() {
+	RootCmd.AddCommand(warmupPlanCmd)
+	warmupPlanCmd.Flags().String("ramp", "",
+		"Comma separated numbers of the recipients per day, eg, 50,100,500.")
+	warmupPlanCmd.Flags().String("start", "",
+		"Send time of the first day as UNIX timestamp or RFC 3339 time (default is now).")
+	warmupPlanCmd.Flags().Int("from-day", 1, "First day of the plan to schedule.")
+	warmupPlanCmd.Flags().Bool("plan-only", false, "Print the plan without scheduling the sends.")
+}
+
+// Statuses of the warmup plan days
+const (
+	warmupPlanned   = "planned"
+	warmupScheduled = "scheduled"
+	warmupPending   = "pending" // beyond the scheduling window
+	warmupSkipped   = "skipped" // before --from-day
+)
+
+// Day of the warmup plan
+type warmupDay struct {
+	Day        int       `json:"day"`
+	SendAt     time.Time `json:"send_at"`
+	Recipients []string  `json:"recipients"`
+	BatchID    string    `json:"batch_id,omitempty"`
+	Status     string    `json:"status"`
+}
+
+// Parses the warmup ramp given as comma separated positive numbers.
+func parseRamp(raw string) ([]int, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, errors.New("the warmup ramp is required, eg, --ramp 50,100,500")
+	}
+	var ramp []int
+	for _, v := range strings.Split(raw, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("incorrect warmup ramp %q, expected comma separated positive numbers", raw)
+		}
+		ramp = append(ramp, n)
+	}
+	return ramp, nil
+}
+
+// Splits the recipients into the days following the ramp starting at start,
+// a day apart. The last volume of the ramp is repeated until all the
+// recipients are planned.
+func planWarmup(tos []string, ramp []int, start time.Time) []warmupDay {
+	var plan []warmupDay
+	for i := 0; len(tos) > 0; i++ {
+		n := ramp[len(ramp)-1]
+		if i < len(ramp) {
+			n = ramp[i]
+		}
+		if n > len(tos) {
+			n = len(tos)
+		}
+		plan = append(plan, warmupDay{
+			Day:        i + 1,
+			SendAt:     start.Add(time.Duration(i) * 24 * time.Hour),
+			Recipients: tos[:n],
+			Status:     warmupPlanned,
+		})
+		tos = tos[n:]
+	}
+	return plan
+}
+
+func warmupPlan(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	ramp, err := parseRamp(flagString(cmd, "ramp"))
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	start := now()
+	if raw := flagString(cmd, "start"); raw != "" {
+		if start, err = parseSendTime(raw); err != nil {
+			fail(exitUsage, err)
+		}
+	}
+	fromDay := flagInt(cmd, "from-day")
+	if fromDay < 1 {
+		fail(exitUsage, "--from-day should be positive.")
+	}
+	if flagString(cmd, "send-at") != "" {
+		fail(exitUsage, "--send-at cannot be combined with warmup-plan, use --start.")
+	}
+
+	p, apiKey, _, _ := prepareSend(cmd, args)
+	if apiKey == "" {
+		fail(exitUsage, "The scheduled sends require SendGrid API key.")
+	}
+	if len(p.sendAts) > 0 {
+		fail(exitUsage, "The per-recipient send times cannot be combined with warmup-plan.")
+	}
+	plan := planWarmup(p.tos, ramp, start)
+
+	planOnly, dryRun := flagBool(cmd, "plan-only"), flagBool(cmd, "dry-run")
+	limit, pending := now().Add(maxSendAtWindow), 0
+	for i := range plan {
+		d := &plan[i]
+		switch {
+		case d.Day < fromDay:
+			d.Status = warmupSkipped
+			continue
+		case d.SendAt.After(limit):
+			d.Status = warmupPending
+			pending++
+			continue
+		case planOnly:
+			continue
+		}
+		if !dryRun {
+			rest.DefaultClient.HTTPClient.Transport = newTransport()
+			if d.BatchID, err = createBatch(apiKey); err != nil {
+				log.Errorf("Failed to create the batch of the day %d.", d.Day)
+				fail(errorExitCode(err), err)
+			}
+		}
+		day := *p
+		day.tos, day.sendAt, day.batchID = d.Recipients, int(d.SendAt.Unix()), d.BatchID
+		day.quiet = true // the plan is printed instead of the summaries
+		if dryRun {
+			day.dryRunOut = os.Stderr // the messages of the dry run are printed apart from the plan
+		}
+		if d.Day > 1 {
+			day.ccs, day.bccs = nil, nil // only the first day goes to CC and BCC recipients
+		}
+		deliverSend(cmd, &day, apiKey, "", "")
+		if !dryRun {
+			d.Status = warmupScheduled
+			log.Infof("Scheduled the day %d to %d recipient(s) at %s.", d.Day, len(d.Recipients), d.SendAt.Format(time.RFC3339))
+		}
+	}
+
+	rows := make([][]string, len(plan))
+	for i, d := range plan {
+		rows[i] = []string{
+			strconv.Itoa(d.Day), d.SendAt.Format(time.RFC3339), strconv.Itoa(len(d.Recipients)), d.BatchID, d.Status}
+	}
+	err = printOutput(cmd, plan, []string{"DAY", "SEND AT", "RECIPIENTS", "BATCH ID", "STATUS"}, rows)
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	if pending > 0 && !planOnly {
+		for _, d := range plan {
+			if d.Status == warmupPending {
+				log.Warnf("%d days are beyond --max-send-at-window, rerun with --from-day %d after %s to schedule them.",
+					pending, d.Day, d.SendAt.Add(-maxSendAtWindow).Format(time.RFC3339))
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRamp(t *testing.T) {
+	ramp, err := parseRamp("50, 100,500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ramp, []int{50, 100, 500}) {
+		t.Errorf("Unexpected ramp: %v", ramp)
+	}
+	for _, raw := range []string{"", "50,,100", "50,0", "-1", "many"} {
+		if _, err := parseRamp(raw); err == nil {
+			t.Errorf("Expected an error for the ramp %q", raw)
+		}
+	}
+}
+
+func TestPlanWarmup(t *testing.T) {
+	start := time.Date(2017, 10, 2, 9, 0, 0, 0, time.UTC)
+	tos := []string{"a@b.c", "b@b.c", "c@b.c", "d@b.c", "e@b.c", "f@b.c", "g@b.c", "h@b.c"}
+	plan := planWarmup(tos, []int{1, 2, 3}, start)
+
+	expected := [][]string{tos[:1], tos[1:3], tos[3:6], tos[6:]}
+	if len(plan) != len(expected) {
+		t.Fatalf("Expected %d days, got %+v", len(expected), plan)
+	}
+	for i, d := range plan {
+		if d.Day != i+1 || !reflect.DeepEqual(d.Recipients, expected[i]) || d.Status != warmupPlanned {
+			t.Errorf("Unexpected day %d: %+v", i+1, d)
+		}
+		if sendAt := start.AddDate(0, 0, i); !d.SendAt.Equal(sendAt) {
+			t.Errorf("Expected the day %d to be sent at %v, got %v", i+1, sendAt, d.SendAt)
+		}
+	}
+
+	if plan := planWarmup(tos[:2], []int{50, 100}, start); len(plan) != 1 || len(plan[0].Recipients) != 2 {
+		t.Errorf("Expected a single day for the short list, got %+v", plan)
+	}
+}
+
+func TestWarmupPlanJSON(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return time.Date(2017, 10, 1, 10, 0, 0, 0, time.UTC) }
+	batches, sends := 0, 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/mail/batch":
+			batches++
+			fmt.Fprintf(w, `{"batch_id": "BATCH-%d"}`, batches)
+		case "/v3/mail/send":
+			sends++
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer fakeServer.Close()
+	defer func(h string, w io.Writer) { apiHost, stdout = h, w }(apiHost, stdout)
+	var out bytes.Buffer
+	apiHost, stdout = fakeServer.URL, &out
+
+	toFile := tempFile(t, "a@example.com\nb@example.com\nc@example.com\n")
+	defer os.Remove(toFile)
+	defer func() {
+		for name, value := range map[string]string{
+			"key": "", "to-file": "", "subject": "", "plain-text": "", "output": "table"} {
+			RootCmd.PersistentFlags().Set(name, value)
+			RootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+		warmupPlanCmd.Flags().Set("ramp", "")
+		warmupPlanCmd.Flags().Set("start", "")
+	}()
+	RootCmd.SetArgs([]string{"warmup-plan", "-k", "API-KEY", "--ramp", "1,2", "--start", "2017-10-01T12:00:00Z",
+		"--to-file", toFile, "-s", "Test", "--plain-text", "Test", "--output", "json"})
+	captureLog(func() {
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var plan []warmupDay
+	if err := json.Unmarshal(out.Bytes(), &plan); err != nil {
+		t.Fatalf("Expected the JSON plan only, got %v:\n%s", err, out.String())
+	}
+	if len(plan) != 2 || sends != 2 {
+		t.Fatalf("Expected 2 scheduled days, got %d sends and %+v", sends, plan)
+	}
+	for i, d := range plan {
+		if d.Status != warmupScheduled || d.BatchID != fmt.Sprintf("BATCH-%d", i+1) || len(d.Recipients) != i+1 {
+			t.Errorf("Unexpected day %d: %+v", i+1, d)
+		}
+	}
+}
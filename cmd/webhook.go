@@ -0,0 +1,249 @@
+// Copyright © 2017 Radomirs Cirskis <nad2000@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sendgrid/rest"
+	"github.com/spf13/cobra"
+)
+
+// webhookCmd represents the webhook command
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Manage the event webhook",
+}
+
+// webhookSetCmd represents the webhook set command
+var webhookSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Configure the event webhook",
+	Long: `Configures the event webhook posting the given events to the URL and
+prints the resulting configuration, eg,
+
+sendgrid-cli webhook set -k API-KEY --url https://example.com/events --events delivered,open,click
+
+All the events are posted unless --events is given. With --disable alone
+keeps the configured URL and events and only disables the webhook.
+`,
+	Run: webhookSet,
+}
+
+// webhookTestCmd represents the webhook test command
+var webhookTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Post a test event to the event webhook",
+	Long: `Asks SendGrid to post a test event to the configured event webhook URL
+or to the URL given with --url, eg,
+
+sendgrid-cli webhook test -k API-KEY
+`,
+	Run: webhookTest,
+}
+
+func init() {
+	RootCmd.AddCommand(webhookCmd)
+	webhookCmd.AddCommand(webhookSetCmd)
+	webhookCmd.AddCommand(webhookTestCmd)
+	webhookTestCmd.Flags().String("url", "", "URL the test event gets posted to (default is the configured one).")
+	webhookSetCmd.Flags().String("url", "", "URL the events get posted to.")
+	webhookSetCmd.Flags().String("events", "",
+		"Comma separated events to post: "+strings.Join(webhookEvents, ", ")+" (default is all).")
+	webhookSetCmd.Flags().Bool("disable", false, "Disable the event webhook (keeps the configured one without --url).")
+}
+
+// Events the webhook can post
+var webhookEvents = []string{
+	"processed", "dropped", "deferred", "delivered", "bounce", "open", "click",
+	"spam_report", "unsubscribe", "group_unsubscribe", "group_resubscribe",
+}
+
+// Event webhook settings
+type eventWebhookSettings struct {
+	Enabled          bool   `json:"enabled"`
+	URL              string `json:"url"`
+	Processed        bool   `json:"processed"`
+	Dropped          bool   `json:"dropped"`
+	Deferred         bool   `json:"deferred"`
+	Delivered        bool   `json:"delivered"`
+	Bounce           bool   `json:"bounce"`
+	Open             bool   `json:"open"`
+	Click            bool   `json:"click"`
+	SpamReport       bool   `json:"spam_report"`
+	Unsubscribe      bool   `json:"unsubscribe"`
+	GroupUnsubscribe bool   `json:"group_unsubscribe"`
+	GroupResubscribe bool   `json:"group_resubscribe"`
+}
+
+// Returns the event toggles keyed by the event names.
+func (s *eventWebhookSettings) toggles() map[string]*bool {
+	return map[string]*bool{
+		"processed": &s.Processed, "dropped": &s.Dropped, "deferred": &s.Deferred,
+		"delivered": &s.Delivered, "bounce": &s.Bounce, "open": &s.Open, "click": &s.Click,
+		"spam_report": &s.SpamReport, "unsubscribe": &s.Unsubscribe,
+		"group_unsubscribe": &s.GroupUnsubscribe, "group_resubscribe": &s.GroupResubscribe,
+	}
+}
+
+// Returns the names of the posted events.
+func (s *eventWebhookSettings) events() []string {
+	toggles := s.toggles()
+	var events []string
+	for _, name := range webhookEvents {
+		if *toggles[name] {
+			events = append(events, name)
+		}
+	}
+	return events
+}
+
+// Creates the webhook settings posting the comma separated events (all if
+// empty) to the URL.
+func newWebhookSettings(url, events string) (*eventWebhookSettings, error) {
+	if url == "" {
+		return nil, errors.New("the webhook URL is required, use --url")
+	}
+	s := &eventWebhookSettings{Enabled: true, URL: url}
+	toggles := s.toggles()
+	if strings.TrimSpace(events) == "" {
+		events = strings.Join(webhookEvents, ",")
+	}
+	for _, name := range strings.Split(events, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		toggle, ok := toggles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown webhook event %q, use: %s", name, strings.Join(webhookEvents, ", "))
+		}
+		*toggle = true
+	}
+	return s, nil
+}
+
+// Updates the event webhook settings.
+func updateWebhookSettings(apiKey string, s *eventWebhookSettings) (*eventWebhookSettings, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	response, err := apiRequest(apiKey, rest.Patch, "/v3/user/webhooks/event/settings", nil, body)
+	if err != nil {
+		return nil, err
+	}
+	var updated eventWebhookSettings
+	if err := json.Unmarshal([]byte(response.Body), &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Retrieves the event webhook settings.
+func fetchWebhookSettings(apiKey string) (*eventWebhookSettings, error) {
+	var s eventWebhookSettings
+	if err := apiGet(apiKey, "/v3/user/webhooks/event/settings", nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Disables the event webhook keeping its configured URL and events.
+func disableWebhook(apiKey string) (*eventWebhookSettings, error) {
+	s, err := fetchWebhookSettings(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	s.Enabled = false
+	return updateWebhookSettings(apiKey, s)
+}
+
+// Returned if the event webhook URL is neither configured nor given
+var errNoWebhookURL = errors.New("the event webhook URL is not configured, use webhook set or --url")
+
+// Asks SendGrid to post a test event to the webhook URL, the configured one
+// if the URL is empty. Returns the URL the event was posted to.
+func testWebhook(apiKey, url string) (string, error) {
+	if url == "" {
+		s, err := fetchWebhookSettings(apiKey)
+		if err != nil {
+			return "", err
+		}
+		if url = s.URL; url == "" {
+			return "", errNoWebhookURL
+		}
+	}
+	body, err := json.Marshal(map[string]string{"url": url})
+	if err != nil {
+		return "", err
+	}
+	_, err = apiRequest(apiKey, rest.Post, "/v3/user/webhooks/event/test", nil, body)
+	return url, err
+}
+
+func webhookTest(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	url, err := testWebhook(apiKeyFlag(cmd), flagString(cmd, "url"))
+	if err != nil {
+		log.Error("Failed to test the event webhook.")
+		if err == errNoWebhookURL {
+			fail(exitUsage, err)
+		}
+		fail(errorExitCode(err), err)
+	}
+	log.Infof("The test event was posted to %s.", url)
+}
+
+func webhookSet(cmd *cobra.Command, args []string) {
+	debugCmd(cmd)
+
+	rest.DefaultClient.HTTPClient.Transport = newTransport()
+	if flagBool(cmd, "disable") && flagString(cmd, "url") == "" {
+		if flagString(cmd, "events") != "" {
+			fail(exitUsage, "--events requires --url.")
+		}
+		s, err := disableWebhook(apiKeyFlag(cmd))
+		if err != nil {
+			log.Error("Failed to disable the event webhook.")
+			fail(errorExitCode(err), err)
+		}
+		printWebhookSettings(cmd, s)
+		return
+	}
+	s, err := newWebhookSettings(flagString(cmd, "url"), flagString(cmd, "events"))
+	if err != nil {
+		fail(exitUsage, err)
+	}
+	s.Enabled = !flagBool(cmd, "disable")
+
+	if s, err = updateWebhookSettings(apiKeyFlag(cmd), s); err != nil {
+		log.Error("Failed to configure the event webhook.")
+		fail(errorExitCode(err), err)
+	}
+	printWebhookSettings(cmd, s)
+}
+
+// Prints the webhook settings.
+func printWebhookSettings(cmd *cobra.Command, s *eventWebhookSettings) {
+	rows := [][]string{{s.URL, strconv.FormatBool(s.Enabled), strings.Join(s.events(), ",")}}
+	if err := printOutput(cmd, s, []string{"URL", "ENABLED", "EVENTS"}, rows); err != nil {
+		fail(exitUsage, err)
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewWebhookSettings(t *testing.T) {
+	s, err := newWebhookSettings("https://example.com/events", "Delivered, open,click")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Enabled || !reflect.DeepEqual(s.events(), []string{"delivered", "open", "click"}) {
+		t.Errorf("Unexpected settings: %+v", s)
+	}
+	if s, _ = newWebhookSettings("https://example.com/events", ""); !reflect.DeepEqual(s.events(), webhookEvents) {
+		t.Errorf("Expected all the events by default, got %v", s.events())
+	}
+	if _, err := newWebhookSettings("https://example.com/events", "delivered,read"); err == nil {
+		t.Error("Expected an error for the unknown event")
+	}
+	if _, err := newWebhookSettings("", "delivered"); err == nil {
+		t.Error("Expected an error for the missing URL")
+	}
+}
+
+func TestUpdateWebhookSettings(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/v3/user/webhooks/event/settings" {
+			t.Errorf("Unexpected request %s %q", r.Method, r.URL.Path)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var s map[string]interface{}
+		if err := json.Unmarshal(body, &s); err != nil {
+			t.Fatal(err)
+		}
+		if s["url"] != "https://example.com/events" || s["bounce"] != true || s["open"] != false {
+			t.Errorf("Unexpected webhook settings: %s", body)
+		}
+		w.Write(body)
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	s, _ := newWebhookSettings("https://example.com/events", "bounce,dropped")
+	updated, err := updateWebhookSettings("API-KEY", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(updated, s) {
+		t.Errorf("Expected the updated settings %+v, got %+v", s, updated)
+	}
+}
+
+func TestTestWebhook(t *testing.T) {
+	configuredURL := "https://example.com/events"
+	var posted string
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/user/webhooks/event/settings":
+			json.NewEncoder(w).Encode(&eventWebhookSettings{Enabled: true, URL: configuredURL})
+		case "/v3/user/webhooks/event/test":
+			if r.Method != http.MethodPost {
+				t.Errorf("Expected POST, got %s", r.Method)
+			}
+			var body struct{ URL string }
+			json.NewDecoder(r.Body).Decode(&body)
+			posted = body.URL
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	url, err := testWebhook("API-KEY", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != configuredURL || posted != configuredURL {
+		t.Errorf("Expected the test event posted to the configured URL, got %q, %q", url, posted)
+	}
+	if _, err := testWebhook("API-KEY", "https://example.com/other"); err != nil || posted != "https://example.com/other" {
+		t.Errorf("Expected the test event posted to the given URL, got %q (%v)", posted, err)
+	}
+
+	configuredURL, posted = "", ""
+	if _, err := testWebhook("API-KEY", ""); err != errNoWebhookURL || posted != "" {
+		t.Errorf("Expected the missing URL error without posting, got %v", err)
+	}
+}
+
+func TestDisableWebhook(t *testing.T) {
+	configured := &eventWebhookSettings{Enabled: true, URL: "https://example.com/events", Bounce: true}
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(configured)
+		case http.MethodPatch:
+			body, _ := ioutil.ReadAll(r.Body)
+			var s eventWebhookSettings
+			if err := json.Unmarshal(body, &s); err != nil {
+				t.Fatal(err)
+			}
+			if s.Enabled || s.URL != configured.URL || !s.Bounce || s.Open {
+				t.Errorf("Expected only the webhook disabled, got %s", body)
+			}
+			w.Write(body)
+		default:
+			t.Errorf("Unexpected request %s %q", r.Method, r.URL.Path)
+		}
+	}))
+	defer fakeServer.Close()
+	defer func(h string) { apiHost = h }(apiHost)
+	apiHost = fakeServer.URL
+
+	s, err := disableWebhook("API-KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Enabled || s.URL != configured.URL {
+		t.Errorf("Unexpected settings: %+v", s)
+	}
+}
@@ -12,20 +12,21 @@ import (
 
 // SGMail is representation of a valid SendGrid Mail
 type SGMail struct {
-	To       []string
-	ToName   []string
-	Cc       []string
-	Subject  string
-	Text     string
-	HTML     string
-	From     string
-	Bcc      []string
-	FromName string
-	ReplyTo  string
-	Date     string
-	Files    map[string]string
-	Content  map[string]string
-	Headers  map[string]string
+	To        []string
+	ToName    []string
+	Cc        []string
+	Subject   string
+	Text      string
+	HTML      string
+	From      string
+	Bcc       []string
+	FromName  string
+	ReplyTo   string
+	Date      string
+	Files     map[string]string
+	FileTypes map[string]string // content types of the attachments by their names
+	Content   map[string]string
+	Headers   map[string]string
 	smtpapi.SMTPAPIHeader
 }
 
@@ -225,6 +226,15 @@ func (m *SGMail) AddAttachmentFromStream(filename, file string) {
 	m.Files[filename] = file
 }
 
+// SetAttachmentType sets the content type of the attachment. The type is
+// guessed by the attachment's extension if it is not set.
+func (m *SGMail) SetAttachmentType(filename, contentType string) {
+	if m.FileTypes == nil {
+		m.FileTypes = make(map[string]string)
+	}
+	m.FileTypes[filename] = contentType
+}
+
 // AddContentID ...
 func (m *SGMail) AddContentID(id, value string) {
 	if m.Content == nil {
@@ -2,22 +2,40 @@
 package sendgrid
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
 const Version = "2.0.0"
 
+// Error is returned when SendGrid responds with an unsuccessful status code
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("sendgrid.go: code:%d body:%s", e.StatusCode, e.Body)
+}
+
 // SGClient will contain the credentials and default values
 type SGClient struct {
-	apiUser string
-	apiPwd  string
-	APIMail string
-	Client  *http.Client
+	apiUser    string
+	apiPwd     string
+	APIMail    string
+	Client     *http.Client
+	UserAgent  string // overrides the default User-Agent header if set
+	StatusCode int    // status code of the last response
 }
 
 // NewSendGridClient will return a new SGClient. Used for username and password
@@ -81,15 +99,62 @@ func (sg *SGClient) buildURL(m *SGMail) (url.Values, error) {
 	for i := 0; i < len(m.ToName); i++ {
 		values.Add("toname[]", m.ToName[i])
 	}
-	for k, v := range m.Files {
-		values.Set("files["+k+"]", v)
-	}
 	for k, v := range m.Content {
 		values.Set("content["+k+"]", v)
 	}
 	return values, nil
 }
 
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// Encodes the values and the attachments as a multipart form. The attachments
+// are sent as "files[<name>]" parts with their content types.
+func multipartForm(values url.Values, m *SGMail) (string, []byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range values[k] {
+			if err := writer.WriteField(k, v); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	names := make([]string, 0, len(m.Files))
+	for name := range m.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		contentType := m.FileTypes[name]
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(name))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[%s]"; filename="%s"`,
+			quoteEscaper.Replace(name), quoteEscaper.Replace(name)))
+		h.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(h)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err = part.Write([]byte(m.Files[name])); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", nil, err
+	}
+	return writer.FormDataContentType(), body.Bytes(), nil
+}
+
 // Send will send mail using SG web API
 func (sg *SGClient) Send(m *SGMail) error {
 	if sg.Client == nil {
@@ -103,12 +168,21 @@ func (sg *SGClient) Send(m *SGMail) error {
 	if e != nil {
 		return e
 	}
-	req, e := http.NewRequest("POST", sg.APIMail, strings.NewReader(values.Encode()))
+	contentType, body := "application/x-www-form-urlencoded", []byte(values.Encode())
+	if len(m.Files) > 0 {
+		if contentType, body, e = multipartForm(values, m); e != nil {
+			return e
+		}
+	}
+	req, e := http.NewRequest("POST", sg.APIMail, bytes.NewReader(body))
 	if e != nil {
 		return e
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("User-Agent", "sendgrid/"+Version+";go")
+	if sg.UserAgent != "" {
+		req.Header.Set("User-Agent", sg.UserAgent)
+	}
 
 	// Using API key
 	if sg.apiUser == "" {
@@ -117,8 +191,9 @@ func (sg *SGClient) Send(m *SGMail) error {
 
 	res, e := sg.Client.Do(req)
 	if e != nil {
-		return fmt.Errorf("sendgrid.go: error:%v; response:%v", e, res)
+		return e // keeps the network error type
 	}
+	sg.StatusCode = res.StatusCode
 
 	defer res.Body.Close()
 
@@ -126,7 +201,7 @@ func (sg *SGClient) Send(m *SGMail) error {
 		return nil
 	}
 
-	body, _ := ioutil.ReadAll(res.Body)
+	response, _ := ioutil.ReadAll(res.Body)
 
-	return fmt.Errorf("sendgrid.go: code:%d error:%v body:%s", res.StatusCode, e, body)
+	return &Error{StatusCode: res.StatusCode, Body: string(response)}
 }
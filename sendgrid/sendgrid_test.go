@@ -75,3 +75,53 @@ func TestSendForAuthorizationHeader(t *testing.T) {
 		t.Errorf("Send failed to send email. Returned error: %v", e)
 	}
 }
+
+func TestSendError(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "{\"message\": \"error\"}")
+	}))
+	defer fakeServer.Close()
+	m := NewMail()
+	client := NewSendGridClient(APIUser, APIPassword)
+	client.APIMail = fakeServer.URL
+	m.AddTo("Test! <test@email.com>")
+
+	e, ok := client.Send(m).(*Error)
+	if !ok || e.StatusCode != http.StatusBadRequest {
+		t.Errorf("Send should return *Error with the status code. Returned error: %v", e)
+	}
+}
+
+func TestSendAttachments(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("Send should post a multipart form with the attachments: %v", err)
+		}
+		for field, contentType := range map[string]string{
+			"files[report.pdf]": "application/pdf",
+			"files[notes]":      "text/plain",
+		} {
+			if fhs := r.MultipartForm.File[field]; len(fhs) != 1 || fhs[0].Header.Get("Content-Type") != contentType {
+				t.Errorf("Expected the attachment %q of the type %q, got %v", field, contentType, fhs)
+			}
+		}
+		if r.FormValue("subject") != "Test" {
+			t.Errorf("Expected the subject field, got %q", r.FormValue("subject"))
+		}
+		fmt.Fprintln(w, "{\"message\": \"success\"}")
+	}))
+	defer fakeServer.Close()
+	m := NewMail()
+	client := NewSendGridClient(APIUser, APIPassword)
+	client.APIMail = fakeServer.URL
+	m.AddTo("test@email.com")
+	m.SetSubject("Test")
+	m.AddAttachmentFromStream("report.pdf", "PDF")
+	m.AddAttachmentFromStream("notes", "Notes")
+	m.SetAttachmentType("notes", "text/plain")
+
+	if e := client.Send(m); e != nil {
+		t.Errorf("Send failed to send email. Returned error: %v", e)
+	}
+}